@@ -0,0 +1,16 @@
+package bluesky
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+func (c *client) SearchActorsIter(ctx context.Context, request *SearchActorsRequest, maxResults int) *Iterator[*bsky.ActorDefs_ProfileView] {
+	return newQueryIterator(ctx, request, maxResults, c.SearchActors,
+		func(req *SearchActorsRequest) *string { return &req.Cursor },
+		func(out *bsky.ActorSearchActors_Output) ([]*bsky.ActorDefs_ProfileView, *string) {
+			return out.Actors, out.Cursor
+		},
+		func(actor *bsky.ActorDefs_ProfileView) string { return actor.Did })
+}
@@ -0,0 +1,62 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getActorStr(did string) string {
+	return fmt.Sprintf(`{"did": "%s", "handle": "test.bsky.social", "displayName": "Test"}`, did)
+}
+
+// Tests that the actor search iterator follows cursors until they run out,
+// skipping accounts that were already seen on a previous page.
+func TestSearchActorsIter(t *testing.T) {
+	mockTransport := newPagesRoundTripper("app.bsky.actor.searchActors", map[string]string{
+		"":  fmt.Sprintf(`{"actors": [%s, %s], "cursor": "2"}`, getActorStr("did:plc:a"), getActorStr("did:plc:b")),
+		"2": fmt.Sprintf(`{"actors": [%s, %s]}`, getActorStr("did:plc:b"), getActorStr("did:plc:c")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.SearchActorsIter(context.Background(), &SearchActorsRequest{Q: "peterman", Limit: 2}, 0)
+
+	var dids []string
+	for it.Next() {
+		dids = append(dids, it.Item().Did)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"did:plc:a", "did:plc:b", "did:plc:c"}, dids)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/app.bsky.actor.searchActors"))
+}
+
+// Tests the query sent for typeahead suggestions and that invalid limits are
+// rejected before reaching the server.
+func TestSearchActorsTypeahead(t *testing.T) {
+	var query string
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/app.bsky.actor.searchActorsTypeahead"] = func(req *http.Request) *http.Response {
+		query = req.URL.RawQuery
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"actors": [%s]}`, getActorStr("did:plc:a")))),
+		}
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	out, err := c.SearchActorsTypeahead(context.Background(), &SearchActorsTypeaheadRequest{Q: "pet", Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "limit=5&q=pet", query)
+	assert.Equal(t, "did:plc:a", out.Actors[0].Did)
+
+	_, err = c.SearchActorsTypeahead(context.Background(), &SearchActorsTypeaheadRequest{Q: "pet", Limit: 500})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.actor.searchActorsTypeahead"))
+}
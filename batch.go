@@ -0,0 +1,278 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+// applyWritesMaxOps is the most writes the Bluesky PDSs accept in a single
+// com.atproto.repo.applyWrites call.
+const applyWritesMaxOps = 200
+
+// ErrWriteNotApplied is reported for the writes of a batch which were never
+// sent because an earlier chunk of the batch failed.
+var ErrWriteNotApplied = errors.New("write not applied")
+
+// WriteAction is the kind of change a write makes to a record.
+type WriteAction string
+
+const (
+	WriteCreate WriteAction = "create"
+	WriteUpdate WriteAction = "update"
+	WriteDelete WriteAction = "delete"
+)
+
+// WriteBatch collects record writes to the logged in account's repository and
+// applies them with com.atproto.repo.applyWrites, e.g.
+//
+//	results, err := client.WriteBatch().
+//		Create("app.bsky.feed.post", "", post).
+//		Delete("app.bsky.feed.like", "3l4m5n6o7p8q9").
+//		Apply(ctx)
+//
+// Batches larger than the server accepts in a single call are split into
+// chunks. Each chunk is applied atomically, and a failing chunk stops the rest
+// of the batch from being sent.
+//
+// Writes are checked as they are added. Invalid ones are left out of the batch
+// and reported by Err and Apply as a *ValidationError.
+type WriteBatch struct {
+	client     *client
+	writes     []*atproto.RepoApplyWrites_Input_Writes_Elem
+	swapCommit string // Expected repository commit CID, if any
+	validate   *bool  // Whether the server validates records against their lexicon
+
+	added int          // Number of writes added, including invalid ones
+	errs  []FieldError // Invalid values passed to the builder
+}
+
+// WriteResult is the outcome of a single write of a batch.
+type WriteResult struct {
+	Action     WriteAction
+	Collection string
+	Rkey       string // Record key, assigned by the server for creates without one
+	Uri        string // AT-URI of the record, empty if not applied
+	Cid        string // CID of the written record, empty for deletes and if not applied
+
+	ValidationStatus string // Whether the server validated the record, if reported
+	Commit           string // CID of the repository commit applying the write
+
+	Err error // Why the write was not applied, nil if it was
+}
+
+func (c *client) WriteBatch() *WriteBatch {
+	return &WriteBatch{client: c}
+}
+
+// Create adds the creation of a record. An empty rkey lets the server assign a
+// TID as record key.
+func (b *WriteBatch) Create(collection string, rkey string, record util.CBOR) *WriteBatch {
+	if !b.check(WriteCreate, collection, rkey, record) {
+		return b
+	}
+	write := &atproto.RepoApplyWrites_Create{
+		Collection: collection,
+		Value:      &util.LexiconTypeDecoder{Val: record},
+	}
+	if rkey != "" {
+		write.Rkey = &rkey
+	}
+	b.writes = append(b.writes, &atproto.RepoApplyWrites_Input_Writes_Elem{RepoApplyWrites_Create: write})
+	return b
+}
+
+// Update adds the replacement of an existing record.
+func (b *WriteBatch) Update(collection string, rkey string, record util.CBOR) *WriteBatch {
+	if !b.check(WriteUpdate, collection, rkey, record) {
+		return b
+	}
+	b.writes = append(b.writes, &atproto.RepoApplyWrites_Input_Writes_Elem{RepoApplyWrites_Update: &atproto.RepoApplyWrites_Update{
+		Collection: collection,
+		Rkey:       rkey,
+		Value:      &util.LexiconTypeDecoder{Val: record},
+	}})
+	return b
+}
+
+// Delete adds the deletion of an existing record.
+func (b *WriteBatch) Delete(collection string, rkey string) *WriteBatch {
+	if !b.check(WriteDelete, collection, rkey, nil) {
+		return b
+	}
+	b.writes = append(b.writes, &atproto.RepoApplyWrites_Input_Writes_Elem{RepoApplyWrites_Delete: &atproto.RepoApplyWrites_Delete{
+		Collection: collection,
+		Rkey:       rkey,
+	}})
+	return b
+}
+
+// SwapCommit makes the batch fail unless the repository is still at the given
+// commit CID, guarding against concurrent changes. Later chunks of a large batch
+// expect the commit made by the previous chunk, and are not sent if the server
+// doesn't report it.
+func (b *WriteBatch) SwapCommit(cid string) *WriteBatch {
+	if _, err := syntax.ParseCID(cid); err != nil {
+		b.errs = append(b.errs, FieldError{Field: "SwapCommit", Param: "swapCommit", Value: cid, Reason: fmt.Sprintf("must be a valid cid: %v", err)})
+		return b
+	}
+	b.swapCommit = cid
+	return b
+}
+
+// Validate requires the server to validate all records against their lexicon
+// if true, or skips validation if false. By default only records of lexicons
+// known to the server are validated.
+func (b *WriteBatch) Validate(validate bool) *WriteBatch {
+	b.validate = &validate
+	return b
+}
+
+// Len returns the number of writes in the batch.
+func (b *WriteBatch) Len() int {
+	return len(b.writes)
+}
+
+// Err returns a *ValidationError listing every invalid value passed to the
+// builder, or nil if all of them were accepted.
+func (b *WriteBatch) Err() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Method: "com.atproto.repo.applyWrites", Fields: b.errs}
+}
+
+// Apply sends the writes in chunks the server accepts, returning a result for
+// each of them in the order they were added. If a chunk fails, its error is
+// returned along with all results, the failed and unsent writes carrying their
+// reason in Err.
+func (b *WriteBatch) Apply(ctx context.Context) ([]*WriteResult, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	results := make([]*WriteResult, len(b.writes))
+	for i, write := range b.writes {
+		results[i] = newWriteResult(write)
+	}
+	swapCommit := b.swapCommit
+	for start := 0; start < len(b.writes); start += applyWritesMaxOps {
+		end := min(start+applyWritesMaxOps, len(b.writes))
+
+		input := &atproto.RepoApplyWrites_Input{
+			Repo:     b.client.did(),
+			Validate: b.validate,
+			Writes:   b.writes[start:end],
+		}
+		if swapCommit != "" {
+			input.SwapCommit = &swapCommit
+		}
+		var out atproto.RepoApplyWrites_Output
+		if err := b.client.do(ctx, xrpc.Procedure, "application/json", "com.atproto.repo.applyWrites", nil, input, &out); err != nil {
+			log.Err(err).Msg("Failed to apply writes.")
+			for _, result := range results[start:end] {
+				result.Err = err
+			}
+			for _, result := range results[end:] {
+				result.Err = ErrWriteNotApplied
+			}
+			return results, err
+		}
+		var commit string
+		if out.Commit != nil {
+			commit = out.Commit.Cid
+		}
+		for i, result := range results[start:end] {
+			result.Commit = commit
+			if i < len(out.Results) {
+				result.applied(out.Results[i])
+			}
+			if result.Uri == "" && result.Rkey != "" {
+				result.Uri = "at://" + input.Repo + "/" + result.Collection + "/" + result.Rkey
+			}
+		}
+		if swapCommit != "" {
+			// Sending the rest unguarded would silently drop the swap
+			if commit == "" && end < len(b.writes) {
+				err := fmt.Errorf("%w: server reported no commit to swap the writes after %d against", ErrWriteNotApplied, end)
+				log.Err(err).Msg("Failed to apply writes.")
+				for _, result := range results[end:] {
+					result.Err = ErrWriteNotApplied
+				}
+				return results, err
+			}
+			swapCommit = commit
+		}
+	}
+	return results, nil
+}
+
+// check validates the target and record of a write, recording any violations.
+// Only creates may leave the record key to the server, and deletes carry no
+// record.
+func (b *WriteBatch) check(action WriteAction, collection string, rkey string, record util.CBOR) bool {
+	b.added++
+
+	reason := ""
+	if _, err := syntax.ParseNSID(collection); err != nil {
+		reason = fmt.Sprintf("must have a valid collection NSID: %v", err)
+	} else if _, err := syntax.ParseRecordKey(rkey); err != nil && !(action == WriteCreate && rkey == "") {
+		reason = fmt.Sprintf("must have a valid record key: %v", err)
+	} else if action != WriteDelete {
+		reason = checkRecordValue(record)
+		if reason == "" {
+			if typ, err := recordType(record); err != nil {
+				reason = fmt.Sprintf("must have a lexicon record value: %v", err)
+			} else if typ != collection {
+				reason = fmt.Sprintf("must have a %s record value, got %s", collection, typ)
+			}
+		}
+	}
+	if reason != "" {
+		b.errs = append(b.errs, FieldError{Field: "Writes", Param: "writes", Value: collection + "/" + rkey, Reason: fmt.Sprintf("element %d %s", b.added-1, reason)})
+		return false
+	}
+	return true
+}
+
+// newWriteResult returns the result of a write yet to be applied.
+func newWriteResult(write *atproto.RepoApplyWrites_Input_Writes_Elem) *WriteResult {
+	switch {
+	case write.RepoApplyWrites_Create != nil:
+		result := &WriteResult{Action: WriteCreate, Collection: write.RepoApplyWrites_Create.Collection}
+		if write.RepoApplyWrites_Create.Rkey != nil {
+			result.Rkey = *write.RepoApplyWrites_Create.Rkey
+		}
+		return result
+	case write.RepoApplyWrites_Update != nil:
+		return &WriteResult{Action: WriteUpdate, Collection: write.RepoApplyWrites_Update.Collection, Rkey: write.RepoApplyWrites_Update.Rkey}
+	default:
+		return &WriteResult{Action: WriteDelete, Collection: write.RepoApplyWrites_Delete.Collection, Rkey: write.RepoApplyWrites_Delete.Rkey}
+	}
+}
+
+// applied fills in the outcome of a write as reported by the server.
+func (r *WriteResult) applied(out *atproto.RepoApplyWrites_Output_Results_Elem) {
+	var uri, cid string
+	var status *string
+	switch {
+	case out.RepoApplyWrites_CreateResult != nil:
+		uri, cid, status = out.RepoApplyWrites_CreateResult.Uri, out.RepoApplyWrites_CreateResult.Cid, out.RepoApplyWrites_CreateResult.ValidationStatus
+	case out.RepoApplyWrites_UpdateResult != nil:
+		uri, cid, status = out.RepoApplyWrites_UpdateResult.Uri, out.RepoApplyWrites_UpdateResult.Cid, out.RepoApplyWrites_UpdateResult.ValidationStatus
+	default:
+		return
+	}
+	r.Uri, r.Cid = uri, cid
+	if status != nil {
+		r.ValidationStatus = *status
+	}
+	if parsed, err := syntax.ParseATURI(uri); err == nil {
+		r.Rkey = parsed.RecordKey().String()
+	}
+}
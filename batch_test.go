@@ -0,0 +1,201 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCommitCid is a valid CID to swap repository commits against.
+const testCommitCid = "bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"
+
+// Returns a mock transport applying writes on top of commit, failing calls
+// which expect another commit. Each call advances the commit and is recorded
+// in inputs.
+func newApplyWritesRoundTripper(commit string, inputs *[]map[string]interface{}) *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.applyWrites"] = func(req *http.Request) *http.Response {
+		var input map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&input)
+		*inputs = append(*inputs, input)
+
+		if swap, ok := input["swapCommit"]; ok && swap != commit {
+			return &http.Response{
+				StatusCode: 400,
+				Body:       io.NopCloser(strings.NewReader(`{"error": "InvalidSwap"}`)),
+			}
+		}
+		var results []string
+		for i, write := range input["writes"].([]interface{}) {
+			write := write.(map[string]interface{})
+			action := strings.TrimPrefix(write["$type"].(string), "com.atproto.repo.applyWrites#")
+			if action == "delete" {
+				results = append(results, `{"$type": "com.atproto.repo.applyWrites#deleteResult"}`)
+				continue
+			}
+			rkey, ok := write["rkey"].(string)
+			if !ok {
+				rkey = fmt.Sprintf("tid%d-%d", len(*inputs), i)
+			}
+			uri := "at://" + input["repo"].(string) + "/" + write["collection"].(string) + "/" + rkey
+			results = append(results, `{"$type": "com.atproto.repo.applyWrites#`+action+`Result", "uri": "`+uri+`", "cid": "cid-`+rkey+`", "validationStatus": "valid"}`)
+		}
+		commit = fmt.Sprintf("commit-%d", len(*inputs))
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"commit": {"cid": "` + commit + `", "rev": "rev"}, "results": [` + strings.Join(results, ",") + `]}`)),
+		}
+	}
+	return mockTransport
+}
+
+// Tests that each write of a batch gets a result, in order.
+func TestWriteBatch(t *testing.T) {
+	var inputs []map[string]interface{}
+	c := newMockClient(t, newApplyWritesRoundTripper(testCommitCid, &inputs))
+	defer c.Close()
+
+	results, err := c.WriteBatch().
+		Create(postCollection, "", &bsky.FeedPost{Text: "new"}).
+		Create(postCollection, "custom", &bsky.FeedPost{Text: "keyed"}).
+		Update("app.bsky.actor.profile", "self", &bsky.ActorProfile{}).
+		Delete("app.bsky.feed.like", "3l4m5n6o7p8q9").
+		Validate(true).
+		Apply(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, inputs, 1) || !assert.Len(t, results, 4) {
+		return
+	}
+	assert.Equal(t, true, inputs[0]["validate"])
+	assert.NotContains(t, inputs[0], "swapCommit")
+
+	assert.Equal(t, WriteCreate, results[0].Action)
+	assert.Equal(t, "tid1-0", results[0].Rkey)
+	assert.Equal(t, "at://did:plc:test/app.bsky.feed.post/tid1-0", results[0].Uri)
+	assert.Equal(t, "cid-tid1-0", results[0].Cid)
+	assert.Equal(t, "valid", results[0].ValidationStatus)
+	assert.Equal(t, "custom", results[1].Rkey)
+	assert.Equal(t, WriteUpdate, results[2].Action)
+	assert.Equal(t, "cid-self", results[2].Cid)
+	assert.Equal(t, WriteDelete, results[3].Action)
+	assert.Equal(t, "at://did:plc:test/app.bsky.feed.like/3l4m5n6o7p8q9", results[3].Uri)
+	assert.Empty(t, results[3].Cid)
+	for _, result := range results {
+		assert.Equal(t, "commit-1", result.Commit)
+		assert.NoError(t, result.Err)
+	}
+}
+
+// Tests that large batches are chunked, each chunk expecting the commit of the
+// previous one.
+func TestWriteBatchChunks(t *testing.T) {
+	var inputs []map[string]interface{}
+	c := newMockClient(t, newApplyWritesRoundTripper(testCommitCid, &inputs))
+	defer c.Close()
+
+	batch := c.WriteBatch().SwapCommit(testCommitCid)
+	for i := 0; i < 450; i++ {
+		batch.Delete("app.bsky.feed.like", fmt.Sprintf("rkey%d", i))
+	}
+	results, err := batch.Apply(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 450)
+	if assert.Len(t, inputs, 3) {
+		assert.Len(t, inputs[0]["writes"], 200)
+		assert.Len(t, inputs[2]["writes"], 50)
+		assert.Equal(t, testCommitCid, inputs[0]["swapCommit"])
+		assert.Equal(t, "commit-1", inputs[1]["swapCommit"])
+		assert.Equal(t, "commit-2", inputs[2]["swapCommit"])
+	}
+	assert.Equal(t, "rkey449", results[449].Rkey)
+	assert.Equal(t, "commit-3", results[449].Commit)
+}
+
+// Tests that a failing chunk is reported on its writes and stops the batch.
+func TestWriteBatchFailure(t *testing.T) {
+	var inputs []map[string]interface{}
+	c := newMockClient(t, newApplyWritesRoundTripper("bafyreihjwkvzvhxn3nurcclfnkxv2ii5brrllvnhtdgzxchvsobkz6rkoa", &inputs))
+	defer c.Close()
+
+	batch := c.WriteBatch().SwapCommit(testCommitCid)
+	for i := 0; i < 250; i++ {
+		batch.Delete("app.bsky.feed.like", fmt.Sprintf("rkey%d", i))
+	}
+	results, err := batch.Apply(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, inputs, 1)
+	if assert.Len(t, results, 250) {
+		assert.Equal(t, err, results[0].Err)
+		assert.Equal(t, err, results[199].Err)
+		assert.ErrorIs(t, results[200].Err, ErrWriteNotApplied)
+		assert.Empty(t, results[0].Commit)
+	}
+}
+
+// Tests that a swapped batch stops if the server doesn't report the commit the
+// next chunk should expect.
+func TestWriteBatchMissingCommit(t *testing.T) {
+	var inputs []map[string]interface{}
+	mockTransport := newApplyWritesRoundTripper(testCommitCid, &inputs)
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.applyWrites"] = func(req *http.Request) *http.Response {
+		var input map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&input)
+		inputs = append(inputs, input)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"results": []}`)),
+		}
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	batch := c.WriteBatch().SwapCommit(testCommitCid)
+	for i := 0; i < 250; i++ {
+		batch.Delete("app.bsky.feed.like", fmt.Sprintf("rkey%d", i))
+	}
+	results, err := batch.Apply(context.Background())
+	assert.ErrorIs(t, err, ErrWriteNotApplied)
+	assert.Len(t, inputs, 1)
+	if assert.Len(t, results, 250) {
+		assert.NoError(t, results[199].Err)
+		assert.ErrorIs(t, results[200].Err, ErrWriteNotApplied)
+		assert.ErrorIs(t, results[249].Err, ErrWriteNotApplied)
+	}
+}
+
+// Tests that invalid writes are reported before anything is sent.
+func TestWriteBatchValidation(t *testing.T) {
+	var inputs []map[string]interface{}
+	c := newMockClient(t, newApplyWritesRoundTripper(testCommitCid, &inputs))
+	defer c.Close()
+
+	batch := c.WriteBatch().
+		Create("not an nsid", "", &bsky.FeedPost{}).
+		Update(postCollection, "", &bsky.FeedPost{}).
+		Create("app.bsky.feed.like", "", &bsky.FeedPost{}).
+		Create(postCollection, "", nil).
+		Create(postCollection, "", (*bsky.FeedPost)(nil)).
+		Update("app.bsky.actor.profile", "self", valueRecord{}).
+		Delete(postCollection, "valid").
+		SwapCommit("not a cid")
+	assert.Equal(t, 1, batch.Len())
+
+	_, err := batch.Apply(context.Background())
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Len(t, verr.Fields, 7)
+		assert.Contains(t, verr.Fields[2].Reason, "element 2 must have a app.bsky.feed.like record value")
+		assert.Contains(t, verr.Fields[4].Reason, "element 4 must have a record value")
+		assert.Contains(t, verr.Fields[5].Reason, "element 5 must be a pointer to a lexicon record")
+		assert.Equal(t, "swapCommit", verr.Fields[6].Param)
+	}
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Empty(t, inputs)
+}
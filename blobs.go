@@ -0,0 +1,74 @@
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *client) UploadBlob(ctx context.Context, r io.Reader, mimeType string) (*util.LexBlob, error) {
+	// No lexicon limits blobs in general, the PDS enforces its own size limit
+	data, err := readBlob(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, reason := checkBlob(data, mimeType, "", 0)
+	if reason != "" {
+		return nil, &ValidationError{Method: "com.atproto.repo.uploadBlob", Fields: []FieldError{{Field: "blob", Param: "blob", Value: mimeType, Reason: reason}}}
+	}
+	return c.uploadBlob(ctx, data, mimeType)
+}
+
+// uploadBlob stores already checked data in the client's repository.
+func (c *client) uploadBlob(ctx context.Context, data []byte, mimeType string) (*util.LexBlob, error) {
+	var out atproto.RepoUploadBlob_Output
+	if err := c.do(ctx, xrpc.Procedure, mimeType, "com.atproto.repo.uploadBlob", nil, bytes.NewReader(data), &out); err != nil {
+		log.Err(err).Msg("Failed to upload blob.")
+		return nil, err
+	}
+	return out.Blob, nil
+}
+
+// readBlob reads a blob into memory, reading at most one byte more than max so
+// that oversized blobs are detected without buffering all of them. A max of 0
+// reads all of r.
+func readBlob(r io.Reader, max int64) ([]byte, error) {
+	if r == nil {
+		return nil, nil
+	}
+	if max == 0 {
+		return io.ReadAll(r)
+	}
+	return io.ReadAll(io.LimitReader(r, max+1))
+}
+
+// checkBlob validates blob data against a size limit, unless 0, and an accepted
+// MIME type prefix such as "image/". A missing MIME type is sniffed from the
+// data. It returns the MIME type and the violated constraint, if any.
+func checkBlob(data []byte, mimeType string, accept string, max int64) (string, string) {
+	if len(data) == 0 {
+		return mimeType, "must not be empty"
+	}
+	if max > 0 && int64(len(data)) > max {
+		return mimeType, fmt.Sprintf("must be at most %d bytes", max)
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return mimeType, fmt.Sprintf("must have a valid MIME type: %v", err)
+	}
+	if len(mediaType) < len(accept) || mediaType[:len(accept)] != accept {
+		return mimeType, fmt.Sprintf("must have a %s* MIME type, got %s", accept, mediaType)
+	}
+	return mimeType, ""
+}
@@ -5,34 +5,162 @@
 package bluesky
 
 import (
-	"time"
+	"context"
+	"io"
+	"net/http"
 
+	"github.com/bluesky-social/indigo/api/atproto"
 	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
 )
 
+//go:generate go run ./internal/cmd/genrequests -lexicons lexicons -out requests_gen.go -datetime app.bsky.feed.searchPosts.since,app.bsky.feed.searchPosts.until -enum app.bsky.feed.searchPosts.sort=top|latest -output app.bsky.graph.searchStarterPacks app.bsky.feed.searchPosts app.bsky.actor.searchActors app.bsky.actor.searchActorsTypeahead app.bsky.graph.searchStarterPacks app.bsky.unspecced.getPopularFeedGenerators app.bsky.feed.getTimeline app.bsky.feed.getAuthorFeed app.bsky.feed.getFeed
+
 // Client to interact with AT Protocol PDSs.
 type Client interface {
 	// Close terminates the client, shutting down all pending tasks and background operations.
+	// It is safe to call multiple times, API calls made afterwards fail with ErrClientClosed.
 	Close() error
 
 	// Determines whether the client is ready to start processing requests.
 	Ready() bool
 
 	// Searches bluesky for posts. https://docs.bsky.app/docs/api/app-bsky-feed-search-posts
-	SearchPosts(request *SearchPostsRequest) (*bsky.FeedSearchPosts_Output, error)
-}
+	//
+	// The context governs the whole call: if it is cancelled or its deadline passes
+	// before the server answers, the returned error is ctx.Err() itself rather
+	// than a wrapped transport error. Requests violating the lexicon fail with a
+	// *ValidationError without reaching the server.
+	SearchPosts(ctx context.Context, request *SearchPostsRequest) (*bsky.FeedSearchPosts_Output, error)
+
+	// Searches bluesky for posts, transparently following the result cursors
+	// across pages. At most maxResults posts are returned, 0 meaning no limit.
+	// Posts showing up on multiple pages are only returned once.
+	SearchPostsIter(ctx context.Context, request *SearchPostsRequest, maxResults int) *Iterator[*bsky.FeedDefs_PostView]
+
+	// Searches bluesky for accounts. https://docs.bsky.app/docs/api/app-bsky-actor-search-actors
+	SearchActors(ctx context.Context, request *SearchActorsRequest) (*bsky.ActorSearchActors_Output, error)
+
+	// Searches bluesky for accounts, transparently following the result cursors
+	// across pages. At most maxResults accounts are returned, 0 meaning no limit.
+	SearchActorsIter(ctx context.Context, request *SearchActorsRequest, maxResults int) *Iterator[*bsky.ActorDefs_ProfileView]
+
+	// Suggests accounts whose handle or name starts with a prefix, e.g. for
+	// auto-completion. https://docs.bsky.app/docs/api/app-bsky-actor-search-actors-typeahead
+	SearchActorsTypeahead(ctx context.Context, request *SearchActorsTypeaheadRequest) (*bsky.ActorSearchActorsTypeahead_Output, error)
+
+	// Searches bluesky for starter packs. https://docs.bsky.app/docs/api/app-bsky-graph-search-starter-packs
+	SearchStarterPacks(ctx context.Context, request *SearchStarterPacksRequest) (*GraphSearchStarterPacks_Output, error)
+
+	// Searches bluesky for starter packs, transparently following the result
+	// cursors across pages. At most maxResults packs are returned, 0 meaning no limit.
+	SearchStarterPacksIter(ctx context.Context, request *SearchStarterPacksRequest, maxResults int) *Iterator[*bsky.GraphDefs_StarterPackViewBasic]
+
+	// Lists popular feed generators, optionally filtered by a search query.
+	// https://docs.bsky.app/docs/api/app-bsky-unspecced-get-popular-feed-generators
+	GetPopularFeedGenerators(ctx context.Context, request *GetPopularFeedGeneratorsRequest) (*bsky.UnspeccedGetPopularFeedGenerators_Output, error)
+
+	// Lists popular feed generators, transparently following the result cursors
+	// across pages. At most maxResults feeds are returned, 0 meaning no limit.
+	GetPopularFeedGeneratorsIter(ctx context.Context, request *GetPopularFeedGeneratorsRequest, maxResults int) *Iterator[*bsky.FeedDefs_GeneratorView]
+
+	// Reads the logged in account's home timeline, newest first.
+	// https://docs.bsky.app/docs/api/app-bsky-feed-get-timeline
+	GetTimeline(ctx context.Context, request *GetTimelineRequest) (*bsky.FeedGetTimeline_Output, error)
+
+	// Reads the home timeline, transparently following the result cursors across
+	// pages. At most maxResults posts are returned, 0 meaning no limit.
+	GetTimelineIter(ctx context.Context, request *GetTimelineRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost]
+
+	// Reads the posts and reposts of an account, narrowed down by one of the
+	// AuthorFeed filters.
+	// https://docs.bsky.app/docs/api/app-bsky-feed-get-author-feed
+	GetAuthorFeed(ctx context.Context, request *GetAuthorFeedRequest) (*bsky.FeedGetAuthorFeed_Output, error)
+
+	// Reads the feed of an account, transparently following the result cursors
+	// across pages. At most maxResults posts are returned, 0 meaning no limit.
+	GetAuthorFeedIter(ctx context.Context, request *GetAuthorFeedRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost]
+
+	// Reads a custom feed, given by the AT-URI of its feed generator record.
+	// https://docs.bsky.app/docs/api/app-bsky-feed-get-feed
+	GetFeed(ctx context.Context, request *GetFeedRequest) (*bsky.FeedGetFeed_Output, error)
+
+	// Reads a custom feed, transparently following the result cursors across
+	// pages. At most maxResults posts are returned, 0 meaning no limit.
+	GetFeedIter(ctx context.Context, request *GetFeedRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost]
+
+	// Publishes a new post from the logged in account, returning its AT-URI and
+	// CID. https://docs.bsky.app/docs/advanced-guides/posts
+	CreatePost(ctx context.Context, post *Post) (*atproto.RepoStrongRef, error)
+
+	// Publishes a post in reply to the post at the parent AT-URI, within the
+	// thread the parent belongs to. Returns the AT-URI and CID of the reply.
+	Reply(ctx context.Context, parent string, post *Post) (*atproto.RepoStrongRef, error)
+
+	// Deletes a post of the logged in account, given by its AT-URI with either
+	// the account's DID or handle as authority.
+	DeletePost(ctx context.Context, uri string) error
+
+	// Detects the mentions, links and hashtags in text, returning it annotated
+	// with the matching facets. Mentioned handles are resolved to DIDs, and left
+	// as plain text if there is no such account.
+	// https://docs.bsky.app/docs/advanced-guides/post-richtext
+	DetectFacets(ctx context.Context, text string) (*RichText, error)
+
+	// Posts text too long for a single post as a thread, each part replying to
+	// the previous one. The text is split as by RichText.Split. If posting a part
+	// fails, the parts posted so far are deleted again; should that fail too, the
+	// parts left behind are returned alongside the error.
+	PostThread(ctx context.Context, rt *RichText, opts *ThreadOptions) ([]*atproto.RepoStrongRef, error)
+
+	// Likes a post, given by its AT-URI. If the logged in account already likes
+	// the post, the existing like is returned.
+	Like(ctx context.Context, uri string) (*atproto.RepoStrongRef, error)
+
+	// Removes the logged in account's like of a post, given by its AT-URI. The
+	// like is found through the viewer state of the post, which may lag behind
+	// likes made moments ago. Posts that are not liked are left alone.
+	Unlike(ctx context.Context, uri string) error
+
+	// Reposts a post, given by its AT-URI. If the logged in account already
+	// reposted the post, the existing repost is returned.
+	Repost(ctx context.Context, uri string) (*atproto.RepoStrongRef, error)
+
+	// Removes the logged in account's repost of a post, given by its AT-URI,
+	// found the same way as by Unlike.
+	Unrepost(ctx context.Context, uri string) error
+
+	// Creates a post quoting another one, given by its AT-URI. The post may embed
+	// images, a video or a link card to show alongside the quote.
+	Quote(ctx context.Context, uri string, post *Post) (*atproto.RepoStrongRef, error)
+
+	// Starts a batch of record creates, updates and deletes in the logged in
+	// account's repository, applied together with com.atproto.repo.applyWrites.
+	// https://docs.bsky.app/docs/api/com-atproto-repo-apply-writes
+	WriteBatch() *WriteBatch
+
+	// Deletes a record of the logged in account, given by its AT-URI with either
+	// the account's DID or handle as authority. See the GetRecord, ListRecords
+	// and PutRecord functions for typed access to the other record operations.
+	DeleteRecord(ctx context.Context, uri string) error
+
+	// Uploads a blob, e.g. an image or video, to the logged in account's
+	// repository, for use in records created within the next few minutes. An
+	// empty mimeType is detected from the data.
+	UploadBlob(ctx context.Context, r io.Reader, mimeType string) (*util.LexBlob, error)
+
+	// Uploads up to 4 images and returns an embed showing them in a post.
+	ImagesEmbed(ctx context.Context, images ...*Image) (*bsky.FeedPost_Embed, error)
+
+	// Returns an embed showing a link card in a post, uploading its optional
+	// thumbnail.
+	ExternalEmbed(ctx context.Context, uri string, title string, description string, thumb *Image) (*bsky.FeedPost_Embed, error)
+
+	// Uploads an MP4 video and returns an embed showing it in a post.
+	VideoEmbed(ctx context.Context, video *Video) (*bsky.FeedPost_Embed, error)
 
-type SearchPostsRequest struct {
-	Author   string // at-identifier, format:
-	Cursor   string
-	Domain   string
-	Lang     string
-	Limit    int
-	Mentions string // at-identifier, format:
-	Q        string
-	Since    time.Time
-	Sort     string // [top, latest]
-	Tag      []string
-	Until    time.Time
-	Url      string
+	// Fetches a web page with hc, or http.DefaultClient if nil, and returns an
+	// embed showing its link card in a post. The card is made of the page's
+	// OpenGraph title, description and image, the latter uploaded as thumbnail.
+	LinkCard(ctx context.Context, hc *http.Client, uri string) (*bsky.FeedPost_Embed, error)
 }
@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluesky-social/indigo/api/atproto"
@@ -17,13 +19,13 @@ import (
 )
 
 var (
-	// jwtAsyncRefreshThreshold is the remaining validity time of a JWT token
-	// below which to trigger a session refresh on a background thread (i.e.
+	// jwtAsyncRefreshThreshold is the default remaining validity time of a JWT
+	// token below which to trigger a session refresh on a background thread (i.e.
 	// the client can still be actively used during).
 	jwtAsyncRefreshThreshold = 5 * time.Minute
 
-	// jwtSyncRefreshThreshold is the remaining validity time of a JWT token
-	// below which to trigger a session refresh on a foreground thread (i.e.
+	// jwtSyncRefreshThreshold is the default remaining validity time of a JWT
+	// token below which to trigger a session refresh on a foreground thread (i.e.
 	// the client blocks new API calls until the refresh finishes).
 	jwtSyncRefreshThreshold = 2 * time.Minute
 )
@@ -42,7 +44,17 @@ var (
 	// has expired and a new login from scratch is required.
 	ErrSessionExpired = errors.New("session expired")
 
-	// TODO: add "blusky throttled me" err
+	// ErrRateLimited is returned from any API call if the server throttled the
+	// client. The concrete error is a *RateLimitError with the limit details.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrClientClosed is returned from any API call made after Close.
+	ErrClientClosed = errors.New("client closed")
+
+	// ErrInvalidRequest is returned from any API call whose request parameters
+	// violate the endpoint's lexicon. The concrete error is a *ValidationError
+	// listing the offending fields.
+	ErrInvalidRequest = errors.New("invalid request")
 )
 
 // NewClient creates a new client authenticated to the Bluesky server with the given handle and appkey.
@@ -51,6 +63,12 @@ var (
 // be detected and rejected. For your security, this library will refuse to use
 // your master credentials.
 func NewClient(ctx context.Context, server string, handle string, appkey string, clientOptions ...clientOption) (Client, error) {
+	return newClientInternal(ctx, handle, appkey, newClientOptionalParams(server, handle, appkey, clientOptions))
+}
+
+// newClientOptionalParams applies the client options and fills in defaults for
+// everything left unset.
+func newClientOptionalParams(server string, handle string, appkey string, clientOptions []clientOption) *clientOptionalParams {
 	params := &clientOptionalParams{}
 	for _, opt := range clientOptions {
 		opt(params)
@@ -68,23 +86,43 @@ func NewClient(ctx context.Context, server string, handle string, appkey string,
 		params.clock = &realClockImpl{}
 	}
 
-	// TODO: better way to check if refresherPause is unset?
-	if params.refresherPause.Microseconds() == 0 {
-		params.refresherPause = 5 * time.Minute
+	// Feed the rate limit headers of all responses into the limiter
+	if params.limiter != nil {
+		httpClient := new(http.Client)
+		if params.xrpcClient.Client != nil {
+			*httpClient = *params.xrpcClient.Client
+		}
+		base := httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		httpClient.Transport = &rateLimitTransport{base: base, limiter: params.limiter}
+		params.xrpcClient.Client = httpClient
 	}
 
-	return newClientInternal(ctx, handle, appkey, params)
-}
+	// Re-login with the initial credentials unless the user has a better source
+	if params.reauthenticate && params.credentials == nil {
+		params.credentials = func(context.Context) (string, string, error) {
+			return handle, appkey, nil
+		}
+	}
 
-// Exposed for mocking clock to test jwt refresh semantics.
-type clockInterface interface {
-	Now() time.Time
-}
+	if params.asyncRefreshThreshold <= 0 {
+		params.asyncRefreshThreshold = jwtAsyncRefreshThreshold
+	}
+	if params.syncRefreshThreshold <= 0 {
+		params.syncRefreshThreshold = jwtSyncRefreshThreshold
+	}
+	if params.syncRefreshThreshold > params.asyncRefreshThreshold {
+		log.Warn().Msgf("Sync refresh threshold %v above async threshold %v, capping it.", params.syncRefreshThreshold, params.asyncRefreshThreshold)
+		params.syncRefreshThreshold = params.asyncRefreshThreshold
+	}
 
-type realClockImpl struct{}
+	if params.refreshRetryPause == 0 {
+		params.refreshRetryPause = 30 * time.Second
+	}
 
-func (*realClockImpl) Now() time.Time {
-	return time.Now()
+	return params
 }
 
 // Still need to figure out how to export the real client.
@@ -93,12 +131,57 @@ type client struct {
 	client *xrpc.Client // Underlying XRPC transport connected to the API
 	clock  clockInterface
 
-	ready            bool               // Whether the client is ready to start commiunicating with bluesky.
-	refreshLock      sync.RWMutex       // Lock protecting the following JWT auth fields
-	accessJwtExpire  time.Time          // Expiration time for the current access JWT token
-	refreshJwtExpire time.Time          // Expiration time for the refresh JWT token
-	jwtAsyncRefresh  chan struct{}      // Channel tracking if an async refresher is running
-	jwtRefresherStop chan chan struct{} // Notification channel to stop the JWT refresher
+	rateLimitMaxWait time.Duration      // Longest time to wait out a rate limit before retrying a query, 0 to never retry
+	limiter          RateLimiter        // Client side rate limiter to consult before each call, nil if none
+	store            SessionStore       // Store to persist the session into after every change, nil if none
+	credentials      CredentialProvider // Source of credentials to log in again once the session expires, nil to give up instead
+	hooks            SessionHooks       // Callbacks to notify of session changes
+
+	asyncRefreshThreshold time.Duration // Remaining access JWT validity below which to refresh in the background
+	syncRefreshThreshold  time.Duration // Remaining access JWT validity below which to refresh blocking calls
+
+	ready           atomic.Bool             // Whether the client is ready to start commiunicating with bluesky.
+	session         atomic.Pointer[session] // Current session, replaced as a whole on every change
+	refreshLock     sync.Mutex              // Lock serialising changes to the session
+	jwtAsyncRefresh chan struct{}           // Channel tracking if an async refresher is running
+	sessionChanged  chan struct{}           // Notification channel for the refresher to reschedule
+
+	reactiveRefreshLock sync.Mutex   // Lock protecting the in-flight reactive refresh
+	reactiveRefresh     *refreshCall // Refresh triggered by an expired token response, nil if none running
+
+	lifetime   context.Context    // Context of background operations, cancelled on Close
+	shutdown   context.CancelFunc // Cancels the lifetime context, aborting in-flight refreshes
+	closeLock  sync.Mutex         // Lock protecting the closed flag
+	closed     bool               // Whether Close was called, no new background work is started after
+	background sync.WaitGroup     // Background goroutines Close waits for
+}
+
+// session is an immutable snapshot of the authentication state of a client. It
+// is never modified once created, so it is safe to use without locking.
+type session struct {
+	auth             *xrpc.AuthInfo // Credentials to authenticate API calls with
+	accessJwtIssued  time.Time      // Issue time for the access JWT token
+	accessJwtExpire  time.Time      // Expiration time for the access JWT token
+	refreshJwtExpire time.Time      // Expiration time for the refresh JWT token
+}
+
+// newSession creates a session snapshot from the given credentials, parsing the
+// JWT expiration times out of the tokens.
+func newSession(auth *xrpc.AuthInfo) (*session, error) {
+	accessJwtClaims, err := parseAccessJwtClaims(auth.AccessJwt)
+	if err != nil {
+		return nil, err
+	}
+	refreshJwtClaims, err := parseRefreshJwtClaims(auth.RefreshJwt)
+	if err != nil {
+		return nil, err
+	}
+	return &session{
+		auth:             auth,
+		accessJwtIssued:  time.Unix(accessJwtClaims.IssuedAt, 0),
+		accessJwtExpire:  time.Unix(accessJwtClaims.ExpiresAt, 0),
+		refreshJwtExpire: time.Unix(refreshJwtClaims.ExpiresAt, 0),
+	}, nil
 }
 
 // Claims for ATProto. github.com/golang-jwt/jwt/v5 does not support the alg ES256K yet, which is what
@@ -114,9 +197,17 @@ type atProtoClaims struct {
 type clientOption func(*clientOptionalParams)
 
 type clientOptionalParams struct {
-	clock          clockInterface
-	refresherPause time.Duration
-	xrpcClient     *xrpc.Client
+	clock                 clockInterface
+	limiter               RateLimiter
+	refreshRetryPause     time.Duration
+	asyncRefreshThreshold time.Duration
+	syncRefreshThreshold  time.Duration
+	rateLimitMaxWait      time.Duration
+	store                 SessionStore
+	reauthenticate        bool
+	credentials           CredentialProvider
+	hooks                 SessionHooks
+	xrpcClient            *xrpc.Client
 }
 
 func withClock(c clockInterface) clientOption {
@@ -125,9 +216,26 @@ func withClock(c clockInterface) clientOption {
 	}
 }
 
-func withJwtRefresherSleepFor(duration time.Duration) clientOption {
+// WithRefreshThresholds overrides when the client refreshes its session, based on
+// the remaining validity of the access JWT. Below async, the session is refreshed
+// in the background while API calls carry on; below sync, API calls wait for the
+// refresh to finish. Zero or negative values keep the defaults of 5 and 2 minutes,
+// and sync is capped at async.
+//
+// The thresholds should stay well below the lifetime of the access JWTs the
+// server issues, 2 hours on Bluesky. Sessions issued inside the async window are
+// refreshed again every retry pause, and calls only wait for refreshes of access
+// JWTs that live longer than the sync threshold.
+func WithRefreshThresholds(async time.Duration, sync time.Duration) clientOption {
 	return func(params *clientOptionalParams) {
-		params.refresherPause = duration
+		params.asyncRefreshThreshold = async
+		params.syncRefreshThreshold = sync
+	}
+}
+
+func withRefreshRetryPause(duration time.Duration) clientOption {
+	return func(params *clientOptionalParams) {
+		params.refreshRetryPause = duration
 	}
 }
 
@@ -140,136 +248,260 @@ func withXrpcClient(c *xrpc.Client) clientOption {
 func newClientInternal(ctx context.Context, handle string, appkey string, params *clientOptionalParams) (Client, error) {
 	// Do a sanity check with the server to ensure everything works. We don't
 	// really care about the response as long as we get a meaningful one.
-	if _, err := atproto.ServerDescribeServer(ctx, params.xrpcClient); err != nil {
+	if err := waitLimiter(ctx, params.limiter, "com.atproto.server.describeServer", 1); err != nil {
 		return nil, err
 	}
+	if _, err := atproto.ServerDescribeServer(ctx, params.xrpcClient); err != nil {
+		return nil, contextError(ctx, err)
+	}
 
-	// Authenticate to the Bluesky server
-	sess, err := atproto.ServerCreateSession(ctx, params.xrpcClient, &atproto.ServerCreateSession_Input{
-		Identifier: handle,
-		Password:   appkey,
-	})
+	auth, err := createSession(ctx, params.xrpcClient, params.limiter, handle, appkey)
 	if err != nil {
-		// TODO: need to handle rate limiting errors correctly here. BSky rate limits to
-		// creating 300 sessions a day/ 30 per 5 min: https://docs.bsky.app/docs/advanced-guides/rate-limits#hosted-account-pds-limits
-		// need to switch on err to return an appropriate error for rate limiting.
-		// Might just want to return err
-		return nil, fmt.Errorf("%w: %v", ErrLoginUnauthorized, err)
+		return nil, err
 	}
-	accessJwtClaims, err := parseAccessJwtClaims(sess.AccessJwt)
+	c, err := newClientFromAuth(auth, params)
 	if err != nil {
 		return nil, err
 	}
+	c.saveSession(ctx, auth)
+	c.start(params.refreshRetryPause)
 
-	refreshJwtClaims, err := parseRefreshJwtClaims(sess.RefreshJwt)
-	if err != nil {
+	return c, nil
+}
+
+// createSession authenticates to the Bluesky server from scratch.
+func createSession(ctx context.Context, xrpcClient *xrpc.Client, limiter RateLimiter, handle string, appkey string) (*xrpc.AuthInfo, error) {
+	// Authenticate to the Bluesky server
+	if err := waitLimiter(ctx, limiter, "com.atproto.server.createSession", 1); err != nil {
 		return nil, err
 	}
+	// Log in with a pristine client, the current session might be a dead one
+	loginClient := new(xrpc.Client)
+	*loginClient = *xrpcClient
+	loginClient.Auth = nil
 
-	// Construct the authenticated client and the JWT expiration metadata
-	c := &client{
-		client: params.xrpcClient,
-		clock:  params.clock,
-		ready:  false,
+	sess, err := atproto.ServerCreateSession(ctx, loginClient, &atproto.ServerCreateSession_Input{
+		Identifier: handle,
+		Password:   appkey,
+	})
+	if err != nil {
+		// A cancelled login says nothing about the credentials, don't report them as bad
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		// Neither does a throttled one. BSky rate limits to creating 300 sessions a day/
+		// 30 per 5 min: https://docs.bsky.app/docs/advanced-guides/rate-limits#hosted-account-pds-limits
+		if rlErr := asRateLimitError(err); errors.Is(rlErr, ErrRateLimited) {
+			return nil, rlErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLoginUnauthorized, err)
 	}
-	params.xrpcClient.Auth = &xrpc.AuthInfo{
+	return &xrpc.AuthInfo{
 		AccessJwt:  sess.AccessJwt,
 		RefreshJwt: sess.RefreshJwt,
 		Handle:     sess.Handle,
 		Did:        sess.Did,
+	}, nil
+}
+
+// newClientFromAuth constructs a client around an existing session. The JWT
+// refresher is not running yet, start needs to be called for that.
+func newClientFromAuth(auth *xrpc.AuthInfo, params *clientOptionalParams) (*client, error) {
+	sess, err := newSession(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct the authenticated client and the JWT expiration metadata. The
+	// credentials are not set on the XRPC client itself, rather each call uses
+	// the session current at the time it's made.
+	c := &client{
+		client:           params.xrpcClient,
+		clock:            params.clock,
+		rateLimitMaxWait: params.rateLimitMaxWait,
+		limiter:          params.limiter,
+		store:            params.store,
+		credentials:      params.credentials,
+		hooks:            params.hooks,
+
+		asyncRefreshThreshold: params.asyncRefreshThreshold,
+		syncRefreshThreshold:  params.syncRefreshThreshold,
 	}
-	c.accessJwtExpire = time.Unix(accessJwtClaims.ExpiresAt, 0)
-	c.refreshJwtExpire = time.Unix(refreshJwtClaims.ExpiresAt, 0)
+	c.session.Store(sess)
+	c.sessionChanged = make(chan struct{}, 1)
+
+	// Background operations must not inherit the constructor's context, which is
+	// usually scoped to a single request; they live until the client is closed.
+	c.lifetime, c.shutdown = context.WithCancel(context.Background())
 
 	c.jwtAsyncRefresh = make(chan struct{}, 1) // 1 async refresher allowed concurrently
-	c.jwtRefresherStop = make(chan chan struct{})
-	go c.refresher(params.refresherPause)
 
-	c.ready = true
 	return c, nil
 }
 
+// start launches the JWT refresher, after which the client is ready for use.
+// The pause is how long the refresher waits before retrying a failed refresh.
+func (c *client) start(retryPause time.Duration) {
+	c.ready.Store(true)
+	c.spawn(func() { c.refresher(c.lifetime, retryPause) })
+}
+
+// setSession replaces the session of the client and wakes the refresher up to
+// reschedule the next refresh.
+func (c *client) setSession(sess *session) {
+	c.session.Store(sess)
+
+	select {
+	case c.sessionChanged <- struct{}{}:
+	default:
+		// The refresher has a wakeup pending already
+	}
+}
+
+// spawn runs fn on a background goroutine that Close waits for. It does nothing
+// and returns false if the client is already closed.
+func (c *client) spawn(fn func()) bool {
+	c.closeLock.Lock()
+	defer c.closeLock.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		fn()
+	}()
+	return true
+}
+
+// saveSession persists the given session credentials if the client has a session
+// store. Failures are only logged, the session itself is still good to use.
+func (c *client) saveSession(ctx context.Context, auth *xrpc.AuthInfo) {
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Save(ctx, auth); err != nil {
+		log.Err(err).Msg("Failed to persist session.")
+	}
+}
+
 func (c *client) Ready() bool {
-	return c.ready
+	return c.ready.Load()
 }
 
 // Close terminates the client, shutting down all pending tasks and background
-// operations.
+// operations. It is safe to call multiple times and from multiple goroutines.
 func (c *client) Close() error {
-	// TODO: is there anything I need to add here? Closing xRPC client, etc...
-	// any potential resource leaks? obv short term because go is GCed
+	c.closeLock.Lock()
+	if c.closed {
+		c.closeLock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.closeLock.Unlock()
+
 	log.Info().Msg("Shutting down client...")
 
-	if !c.ready {
+	if !c.Ready() {
 		log.Info().Msg("Client not ready when shutting down.")
 	}
 
-	// If the periodical JWT refresher is running, tear it down
-	if c.jwtRefresherStop != nil {
-		// This path is particularly brittle and prone to the refresher not stopping.
-		log.Info().Msg("Found a running JWT refresher.")
-		stopc := make(chan struct{})
-		c.jwtRefresherStop <- stopc
-		<-stopc
+	// Abort any refresh currently talking to the server and wait for the refresher
+	// and all in-flight refreshes to notice. The refresher might have exited on its
+	// own already, which is fine, nothing waits on it to acknowledge.
+	c.shutdown()
+	c.background.Wait()
+	c.ready.Store(false)
 
-		c.jwtRefresherStop = nil
-	}
-
-	c.ready = false
+	log.Info().Msg("Stopped background operations.")
 	return nil
 }
 
-// refresher is an infinite loop that periodically checks the validity of the JWT
-// tokens and runs a refresh cycle if they are getting close to expiration.
-func (c *client) refresher(pause time.Duration) {
+// refresher is an infinite loop that runs a refresh cycle whenever the JWT tokens
+// are getting close to expiration. It sleeps until the access token enters the
+// async refresh window, rescheduling if the session is replaced meanwhile.
+func (c *client) refresher(ctx context.Context, retryPause time.Duration) {
 	for {
 		// Attempt to refresh the JWT token
-		// do we hang if mayberefreshjwt returns an error here?
-		err := c.maybeRefreshJWT()
+		err := c.maybeRefreshJWT(ctx)
 
-		if err == ErrSessionExpired {
+		if errors.Is(err, ErrSessionExpired) {
 			log.Err(err).Msg("Shutting down refresher. Create a new client to continue sending requests.")
+			c.notifySessionExpired(err)
 			return
 		}
 
-		// Wait until some time passes or the client is shutting down
-		select {
-		// TODO check out bluesky's refresh session limits. Probably want to coordinate with that.
-		case <-time.After(pause):
-		case stopc := <-c.jwtRefresherStop:
-			log.Info().Msg("Stopping refresher.")
-			stopc <- struct{}{}
+		if !c.waitNextRefresh(ctx, retryPause) {
 			log.Info().Msg("Stopped refresher.")
 			return
 		}
 	}
 }
 
+// waitNextRefresh sleeps until the next refresh is due, returning false if the
+// client is shutting down instead. A replaced session only reschedules the wait
+// and never triggers a check by itself, so a server issuing tokens that are
+// already inside the refresh window gets a refresh per retry pause, not a loop.
+func (c *client) waitNextRefresh(ctx context.Context, retryPause time.Duration) bool {
+	for {
+		timer := c.clock.NewTimer(c.nextRefreshIn(retryPause))
+		select {
+		case <-timer.C():
+			return true
+		case <-c.sessionChanged:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// nextRefreshIn returns how long the refresher can sleep before the current
+// session needs its attention. If a refresh is already due, i.e. the last one
+// failed, is still running or yielded tokens expiring within the refresh window,
+// the retry pause is returned to avoid spinning.
+func (c *client) nextRefreshIn(retryPause time.Duration) time.Duration {
+	var (
+		sess = c.session.Load()
+		now  = c.clock.Now()
+		wait = min(sess.accessJwtExpire.Sub(now)-c.asyncRefreshThreshold, sess.refreshJwtExpire.Sub(now))
+	)
+	if wait <= 0 {
+		return retryPause
+	}
+	return wait
+}
+
 // maybeRefreshJWT checks the remainder validity time of the JWT token and does
 // a session refresh if it is necessary. Depending on the amount of time it is
 // still valid it might attempt a refresh on a background thread (permitting the
 // current thread to proceed) or blocking the thread and doing a sync refresh.
-func (c *client) maybeRefreshJWT() error {
+func (c *client) maybeRefreshJWT(ctx context.Context) error {
 	log.Info().Msg("Checking JWT for refresh.")
 
 	var (
+		sess              = c.session.Load()
 		now               = c.clock.Now()
-		invalidRefreshJwt = c.refreshJwtExpire.Before(c.clock.Now())
-		needSyncRefresh   = c.accessJwtExpire.Sub(now) < jwtSyncRefreshThreshold
-		needAsyncRefresh  = c.accessJwtExpire.Sub(now) < jwtAsyncRefreshThreshold
+		invalidRefreshJwt = sess.refreshJwtExpire.Before(now)
+		needSyncRefresh   = sess.accessJwtExpire.Sub(now) < c.syncRefreshThreshold
+		needAsyncRefresh  = sess.accessJwtExpire.Sub(now) < c.asyncRefreshThreshold
 	)
 
 	if invalidRefreshJwt {
 		// we shouldn't even attempt to refresh the JWT if our refresh token is not valid
-		// TODO consider trying to do a new CreateSession.
 		log.Err(ErrSessionExpired).Msg("Refresh JWT expiration in the past.")
-		c.ready = false
+		if c.credentials != nil {
+			return c.relogin(ctx, sess, fmt.Errorf("%w: refresh token was valid until %v", ErrSessionExpired, sess.refreshJwtExpire))
+		}
+		c.ready.Store(false)
 		return ErrSessionExpired
 	}
 
 	if needSyncRefresh {
 		log.Info().Msg("Access JWT expires very soon, refreshing synchronously.")
-		return c.refreshJWT()
+		return c.refreshSession(ctx)
 	}
 
 	// If the JWT token is still valid enough for an async refresh, do that and
@@ -279,12 +511,15 @@ func (c *client) maybeRefreshJWT() error {
 		select {
 		case c.jwtAsyncRefresh <- struct{}{}:
 			// We're the first to attempt a background refresh, do it
-			go func() {
-				if err := c.refreshJWT(); err != nil {
+			started := c.spawn(func() {
+				if err := c.refreshSession(ctx); err != nil {
 					log.Error().Err(err).Msg("Async JWT refresh failed.")
 				}
 				<-c.jwtAsyncRefresh
-			}()
+			})
+			if !started {
+				<-c.jwtAsyncRefresh
+			}
 			return nil
 
 		default:
@@ -293,33 +528,109 @@ func (c *client) maybeRefreshJWT() error {
 		}
 	}
 
-	log.Info().Msgf("Current access JWT still valid until %v, current time is %v skipping refresh.", c.accessJwtExpire, c.clock.Now())
+	log.Info().Msgf("Current access JWT still valid until %v, current time is %v skipping refresh.", sess.accessJwtExpire, now)
+	return nil
+}
+
+// refreshSession refreshes the JWT tokens, falling back to logging in again if
+// the server rejects the refresh token as expired or invalid and the client may
+// reauthenticate. Other failures are left for the next refresh to retry, as new
+// logins are far more heavily rate limited.
+func (c *client) refreshSession(ctx context.Context) error {
+	sess := c.session.Load()
+	err := c.refreshJWT(ctx)
+	if c.credentials != nil && (errors.Is(err, ErrSessionExpired) || isTokenExpired(err)) {
+		err = c.relogin(ctx, sess, err)
+	}
+	if err != nil {
+		c.notifyRefreshError(err)
+	}
+	return err
+}
+
+// relogin replaces the failed session of the client with a freshly created one,
+// using credentials from the client's credential provider. The reason is the
+// error that made the old session unusable, reported to the relogin hook.
+// Nothing is done if the failed session was already replaced since.
+func (c *client) relogin(ctx context.Context, failed *session, reason error) error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	if c.session.Load() != failed {
+		log.Info().Msg("Session already replaced, skipping login.")
+		return nil
+	}
+
+	handle, appkey, err := c.credentials(ctx)
+	if err != nil {
+		err = fmt.Errorf("retrieving credentials: %w", err)
+		c.notifyRelogin(ReloginEvent{Reason: reason, Err: err})
+		return err
+	}
+	log.Info().Msgf("Session expired, logging in again as %v.", handle)
+
+	if err := c.login(ctx, handle, appkey); err != nil {
+		c.ready.Store(false)
+		c.notifyRelogin(ReloginEvent{Handle: handle, Reason: reason, Err: err})
+
+		// Retrying with rejected credentials is pointless, give up on the session
+		if errors.Is(err, ErrLoginUnauthorized) {
+			return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+		}
+		return err
+	}
+	sess := c.session.Load()
+	c.saveSession(ctx, sess.auth)
+	c.ready.Store(true)
+	c.notifyRelogin(ReloginEvent{Handle: handle, Did: sess.auth.Did, Reason: reason})
+	c.notifySessionRefreshed(sess)
+
+	return nil
+}
+
+// login creates a new session and swaps out the credentials in the client. The
+// caller must hold the refresh lock.
+func (c *client) login(ctx context.Context, handle string, appkey string) error {
+	auth, err := createSession(ctx, c.client, c.limiter, handle, appkey)
+	if err != nil {
+		return err
+	}
+	sess, err := newSession(auth)
+	if err != nil {
+		return err
+	}
+	c.setSession(sess)
+
 	return nil
 }
 
 // refreshJWT updates the JWT token and swaps out the credentials in the client.
-func (c *client) refreshJWT() error {
+func (c *client) refreshJWT(ctx context.Context) error {
 	c.refreshLock.Lock()
 	defer c.refreshLock.Unlock()
 
-	log.Info().Msgf("Attempting to refresh JWT. Old JWT expired in %v.", c.accessJwtExpire.Sub(c.clock.Now()))
+	old := c.session.Load()
+	log.Info().Msgf("Attempting to refresh JWT. Old JWT expired in %v.", old.accessJwtExpire.Sub(c.clock.Now()))
 
 	// If the refresh token timed out too, bad luck
-	if c.clock.Now().After(c.refreshJwtExpire) {
-		return fmt.Errorf("%w: refresh token was valid until %v", ErrSessionExpired, c.refreshJwtExpire)
+	if c.clock.Now().After(old.refreshJwtExpire) {
+		return fmt.Errorf("%w: refresh token was valid until %v", ErrSessionExpired, old.refreshJwtExpire)
 	}
 
 	// Create a copy of the client for the refresh request
 	newClient := new(xrpc.Client)
 	*newClient = *c.client
 	newClient.Auth = new(xrpc.AuthInfo)
-	*newClient.Auth = *c.client.Auth
+	*newClient.Auth = *old.auth
 	newClient.Auth.AccessJwt = newClient.Auth.RefreshJwt
-	sess, err := atproto.ServerRefreshSession(context.Background(), newClient)
+	if err := waitLimiter(ctx, c.limiter, "com.atproto.server.refreshSession", 1); err != nil {
+		return err
+	}
+	sess, err := atproto.ServerRefreshSession(ctx, newClient)
 	if err != nil {
 		// err might be transient, don't close immediately.
 		// TODO: Do I need to switch on error type to determine whether to close?
-		return err
+		return contextError(ctx, err)
 	}
 
 	refreshTokenClaims, err := parseATProtoClaims(sess.RefreshJwt)
@@ -336,22 +647,176 @@ func (c *client) refreshJWT() error {
 
 	log.Info().Msgf("New access token expiration: %v (in %v)", accessTokenClaims.ExpiresAt, newAccessTokenExpirationTime.Sub(c.clock.Now()))
 
-	if newRefreshTokenExpirationTime.After(c.refreshJwtExpire) {
+	if newRefreshTokenExpirationTime.After(old.refreshJwtExpire) {
 		log.Info().Msgf("Received a new refresh token. New refresh token expiration: %v (in %v)",
 			newRefreshTokenExpirationTime, newRefreshTokenExpirationTime.Sub(c.clock.Now()))
 	}
-	c.client.Auth = &xrpc.AuthInfo{
+	auth := &xrpc.AuthInfo{
 		AccessJwt:  sess.AccessJwt,
 		RefreshJwt: sess.RefreshJwt,
 		Handle:     sess.Handle,
 		Did:        sess.Did,
 	}
-	c.accessJwtExpire = newAccessTokenExpirationTime
-	c.refreshJwtExpire = newRefreshTokenExpirationTime
+	refreshed := &session{
+		auth:             auth,
+		accessJwtIssued:  time.Unix(accessTokenClaims.IssuedAt, 0),
+		accessJwtExpire:  newAccessTokenExpirationTime,
+		refreshJwtExpire: newRefreshTokenExpirationTime,
+	}
+	c.setSession(refreshed)
+	c.saveSession(ctx, auth)
+	c.notifySessionRefreshed(refreshed)
 
 	return nil
 }
 
+// do issues an XRPC call on the underlying transport. It exists so every API
+// call goes through a single place that knows how to surface context and rate
+// limit errors, and how to retry queries the server throttled.
+func (c *client) do(ctx context.Context, kind xrpc.XRPCRequestType, inpenc string, method string, params map[string]interface{}, bodyobj interface{}, out interface{}) error {
+	var (
+		deadline  = c.clock.Now().Add(c.rateLimitMaxWait)
+		refreshed = false
+	)
+	if err := c.awaitSyncRefresh(ctx); err != nil {
+		return err
+	}
+	for {
+		// Don't bother the server if the caller already gave up
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if c.lifetime.Err() != nil {
+			return ErrClientClosed
+		}
+		if err := waitLimiter(ctx, c.limiter, method, rateLimitPoints(method, bodyobj)); err != nil {
+			return err
+		}
+		// Authenticate with a snapshot of the session, so a concurrent refresh
+		// doesn't change the credentials under the transport's feet
+		sess := c.session.Load()
+		xrpcClient := *c.client
+		xrpcClient.Auth = sess.auth
+
+		err := contextError(ctx, asRateLimitError(xrpcClient.Do(ctx, kind, inpenc, method, params, bodyobj, out)))
+
+		// The server might revoke or shorten tokens before the refresher notices,
+		// so refresh right away and retry once. Streamed bodies can't be resent.
+		if _, streamed := bodyobj.(io.Reader); !refreshed && !streamed && isTokenExpired(err) {
+			log.Info().Msgf("Access token rejected calling %v, refreshing session.", method)
+			if err := c.refreshExpiredSession(ctx, sess); err != nil {
+				return err
+			}
+			refreshed = true
+			continue
+		}
+
+		// Only queries are safe to repeat, and only if the user asked for it
+		var rlErr *RateLimitError
+		if kind != xrpc.Query || c.rateLimitMaxWait == 0 || !errors.As(err, &rlErr) {
+			return err
+		}
+		if err := c.waitRateLimit(ctx, rlErr, deadline); err != nil {
+			return err
+		}
+	}
+}
+
+// awaitSyncRefresh makes an API call wait for a session refresh if the access
+// token is within the sync refresh threshold. Access tokens issued with less
+// validity than the threshold are left to the refresher, as no refresh could get
+// them out of the window. A failed refresh only fails the call if the access
+// token is already expired.
+func (c *client) awaitSyncRefresh(ctx context.Context) error {
+	var (
+		sess = c.session.Load()
+		now  = c.clock.Now()
+	)
+	if sess.accessJwtExpire.Sub(now) >= c.syncRefreshThreshold || sess.accessJwtExpire.Sub(sess.accessJwtIssued) <= c.syncRefreshThreshold {
+		return nil
+	}
+	err := c.refreshExpiredSession(ctx, sess)
+	if err != nil && now.Before(sess.accessJwtExpire) && ctx.Err() == nil && !errors.Is(err, ErrClientClosed) {
+		log.Err(err).Msg("Failed to refresh session, calling with the current one.")
+		return nil
+	}
+	return err
+}
+
+// refreshCall is a session refresh shared by all callers that need it.
+type refreshCall struct {
+	done chan struct{} // Closed when the refresh finished
+	err  error         // Outcome of the refresh, valid after done is closed
+}
+
+// refreshExpiredSession refreshes the session after the server rejected the
+// access token of the given one, or it is about to expire. Concurrent callers
+// share a single refresh, and nothing is done if the session was already
+// replaced since.
+func (c *client) refreshExpiredSession(ctx context.Context, stale *session) error {
+	c.reactiveRefreshLock.Lock()
+	if c.session.Load() != stale {
+		c.reactiveRefreshLock.Unlock()
+		return nil
+	}
+	call := c.reactiveRefresh
+	if call == nil {
+		// First to notice, refresh on behalf of everyone. The refresh is bound to
+		// the client's lifetime, so callers giving up don't abort it for others.
+		call = &refreshCall{done: make(chan struct{})}
+
+		started := c.spawn(func() {
+			call.err = c.refreshSession(c.lifetime)
+
+			c.reactiveRefreshLock.Lock()
+			c.reactiveRefresh = nil
+			c.reactiveRefreshLock.Unlock()
+
+			close(call.done)
+		})
+		if !started {
+			c.reactiveRefreshLock.Unlock()
+			return ErrClientClosed
+		}
+		c.reactiveRefresh = call
+	}
+	c.reactiveRefreshLock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isTokenExpired reports whether the server refused a call because the access
+// token it was authenticated with is expired or revoked.
+func isTokenExpired(err error) bool {
+	var xrpcErr *xrpc.Error
+	if !errors.As(err, &xrpcErr) {
+		return false
+	}
+	if xrpcErr.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	var xe *xrpc.XRPCError
+	return errors.As(err, &xe) && (xe.ErrStr == "ExpiredToken" || xe.ErrStr == "InvalidToken")
+}
+
+// contextError replaces err with the context's own error if the context was
+// cancelled or timed out, so callers can tell context.Canceled and
+// context.DeadlineExceeded apart from failures reported by the server.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 func parseAccessJwtClaims(jwt string) (*atProtoClaims, error) {
 	claims, err := parseATProtoClaims(jwt)
 
@@ -377,6 +842,9 @@ func parseATProtoClaims(jwt string) (*atProtoClaims, error) {
 	var claims atProtoClaims
 	// Verify and reject master credentials, sorry, no bad security practices
 	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT: expected 3 parts, got %d", len(parts))
+	}
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
 		return nil, fmt.Errorf("error decoding payload: %v", err)
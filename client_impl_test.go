@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,13 +23,85 @@ import (
 // mock clock to allow us to easily advance time in tests.
 // Used for testing JWT refreshes.
 type mockClock struct {
-	time time.Time
+	lock   sync.Mutex
+	time   time.Time
+	timers []*mockTimer // Timers not fired nor stopped yet
+}
+
+// mockTimer is a timer firing when its mockClock is advanced past its deadline.
+type mockTimer struct {
+	clock    *mockClock
+	deadline time.Time
+	c        chan time.Time
 }
 
 func (c *mockClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	return c.time
 }
 
+func (c *mockClock) NewTimer(d time.Duration) clockTimer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	timer := &mockTimer{clock: c, deadline: c.time.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- c.time
+		return timer
+	}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// advance moves the clock forward by the given duration, firing all the timers
+// that expire in the meantime.
+func (c *mockClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.time = c.time.Add(d)
+
+	var pending []*mockTimer
+	for _, timer := range c.timers {
+		if timer.deadline.After(c.time) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.time
+	}
+	c.timers = pending
+}
+
+// waitForTimers waits until exactly n timers are pending on the clock, i.e. the
+// goroutines under test went to sleep.
+func (c *mockClock) waitForTimers(t *testing.T, n int) {
+	assert.Eventually(t, func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		return len(c.timers) == n
+	}, time.Second, time.Millisecond)
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func getAccessJwt(currentTime time.Time, expiresAt time.Time) string {
 	accessClaims := atProtoClaims{
 		Scope:     "com.atproto.appPass",
@@ -111,14 +184,16 @@ func TestJWTNoopRefresh(t *testing.T) {
 	}
 	assert.True(t, c.Ready())
 
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.describeServer"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.createSession"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.describeServer"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.createSession"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
-	// Advance time and wait a bit to ensure that we don't try to refresh our session as our JWT is still valid.
-	clock.time = clock.time.Add(5 * time.Hour)
-	time.Sleep(100 * time.Millisecond)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	// Advance time and ensure that we don't try to refresh our session as our JWT is still valid.
+	// The refresher stays asleep until the access JWT enters the refresh window.
+	clock.waitForTimers(t, 1)
+	clock.advance(5 * time.Hour)
+	clock.waitForTimers(t, 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
 	c.Close()
 
@@ -156,7 +231,6 @@ func TestJWTAsyncRefresh(t *testing.T) {
 
 	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
 		withClock(clock),
-		withJwtRefresherSleepFor(50*time.Millisecond),
 		withXrpcClient(&xrpc.Client{
 			Client: &http.Client{
 				Transport: mockTransport,
@@ -169,17 +243,19 @@ func TestJWTAsyncRefresh(t *testing.T) {
 	}
 	assert.True(t, c.Ready())
 
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.describeServer"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.createSession"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.describeServer"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.createSession"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
 	// TODO add coverage to ensure we're exercising both the sync and async refresh paths
 
 	// advance time just past async threshold which allows for 5 minute old JWTs.
-	clock.time = clock.time.Add(6 * time.Minute)
+	clock.waitForTimers(t, 1)
+	clock.advance(6 * time.Minute)
 	// JWT now has 4 minutes (10 - 6) left.
-	time.Sleep(100 * time.Millisecond)
-	assert.Greater(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Eventually(t, func() bool {
+		return mockTransport.calls("/xrpc/com.atproto.server.refreshSession") == 1
+	}, time.Second, time.Millisecond)
 
 	c.Close()
 	assert.False(t, c.Ready())
@@ -216,7 +292,6 @@ func TestJWTSyncRefresh(t *testing.T) {
 
 	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
 		withClock(clock),
-		withJwtRefresherSleepFor(50*time.Millisecond),
 		withXrpcClient(&xrpc.Client{
 			Client: &http.Client{
 				Transport: mockTransport,
@@ -229,20 +304,284 @@ func TestJWTSyncRefresh(t *testing.T) {
 	}
 	assert.True(t, c.Ready())
 
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.describeServer"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.createSession"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.describeServer"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.createSession"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
 	// JWT should be sync refreshed given that the access token will have one minute left of its
 	// its original 10 minutes.
-	clock.time = clock.time.Add(9 * time.Minute)
-	time.Sleep(100 * time.Millisecond)
-	assert.Greater(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	clock.waitForTimers(t, 1)
+	clock.advance(9 * time.Minute)
+	assert.Eventually(t, func() bool {
+		return mockTransport.calls("/xrpc/com.atproto.server.refreshSession") == 1
+	}, time.Second, time.Millisecond)
 
 	c.Close()
 	assert.False(t, c.Ready())
 }
 
+// Tests that the refresher wakes up exactly when the access JWT enters the async
+// refresh window, without polling in between.
+func TestJWTRefreshScheduling(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, now.Add(10*time.Minute)), getRefreshJwt(now, now.Add(72*time.Hour))))),
+	}
+	mockTransport.responseMap["/xrpc/com.atproto.server.refreshSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getRefreshSessionResponse(getAccessJwt(now, now.Add(24*time.Hour)), getRefreshJwt(now, now.Add(72*time.Hour))))),
+	}
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	// Just before the refresh window nothing happens
+	clock.waitForTimers(t, 1)
+	clock.advance(10*time.Minute - jwtAsyncRefreshThreshold - time.Second)
+	clock.waitForTimers(t, 1)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+
+	// Entering it triggers the refresh, after which the refresher goes back to
+	// sleep until the new access JWT needs a refresh
+	clock.advance(time.Second)
+	assert.Eventually(t, func() bool {
+		return mockTransport.calls("/xrpc/com.atproto.server.refreshSession") == 1
+	}, time.Second, time.Millisecond)
+	clock.waitForTimers(t, 1)
+}
+
+// Tests that sessions issued inside the refresh window are refreshed once per
+// retry pause, instead of the refresher spinning on every new session.
+func TestJWTRefreshWithinWindow(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+
+	mockTransport := newDefaultMockRoundTripper()
+	twoHourSession := func(req *http.Request) *http.Response {
+		now := clock.Now()
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, now.Add(2*time.Hour)), getRefreshJwt(now, now.Add(72*time.Hour))))),
+		}
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.createSession"] = twoHourSession
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = twoHourSession
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		WithRefreshThresholds(3*time.Hour, time.Minute),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	// The first session triggers a refresh right away, whose result only
+	// reschedules the refresher
+	assert.Eventually(t, func() bool {
+		return mockTransport.calls("/xrpc/com.atproto.server.refreshSession") == 1
+	}, time.Second, time.Millisecond)
+	clock.waitForTimers(t, 1)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+
+	// Each retry pause brings another one
+	clock.advance(30 * time.Second)
+	assert.Eventually(t, func() bool {
+		return mockTransport.calls("/xrpc/com.atproto.server.refreshSession") == 2
+	}, time.Second, time.Millisecond)
+	clock.waitForTimers(t, 1)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+}
+
+// Tests that per-client refresh thresholds move the refresh window, and that the
+// new session is reported to the refreshed hook.
+func TestJWTRefreshThresholds(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	postRefreshAccessExpire := now.Add(24 * time.Hour).Truncate(time.Second)
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, now.Add(time.Hour)), getRefreshJwt(now, now.Add(72*time.Hour))))),
+	}
+	mockTransport.responseMap["/xrpc/com.atproto.server.refreshSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getRefreshSessionResponse(getAccessJwt(now, postRefreshAccessExpire), getRefreshJwt(now, now.Add(72*time.Hour))))),
+	}
+	events := make(chan SessionRefreshedEvent, 1)
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		WithRefreshThresholds(30*time.Minute, 10*time.Minute),
+		WithSessionHooks(SessionHooks{
+			OnSessionRefreshed: func(event SessionRefreshedEvent) { events <- event },
+		}),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	// Just before the custom refresh window nothing happens
+	clock.waitForTimers(t, 1)
+	clock.advance(30*time.Minute - time.Second)
+	clock.waitForTimers(t, 1)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+
+	clock.advance(time.Second)
+	select {
+	case event := <-events:
+		assert.Equal(t, "did:plc:test", event.Auth.Did)
+		assert.True(t, postRefreshAccessExpire.Equal(event.AccessJwtExpire))
+	case <-time.After(time.Second):
+		t.Fatalf("session was not refreshed")
+	}
+}
+
+// Tests that API calls made within the sync refresh window wait for the session
+// to be refreshed and use the new access JWT.
+func TestJWTSyncRefreshBlocksCalls(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	originalAccessJWT := getAccessJwt(now, now.Add(10*time.Minute))
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(originalAccessJWT, getRefreshJwt(now, now.Add(72*time.Hour))))),
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = func(req *http.Request) *http.Response {
+		now := clock.Now()
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(getRefreshSessionResponse(getAccessJwt(now, now.Add(2*time.Hour)), getRefreshJwt(now, now.Add(72*time.Hour))))),
+		}
+	}
+	var auth string
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.searchPosts"] = func(req *http.Request) *http.Response {
+		auth = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"posts": []}`))}
+	}
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	clock.waitForTimers(t, 1)
+	clock.advance(9 * time.Minute)
+
+	_, err = c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 1)
+	assert.NotEqual(t, "Bearer "+originalAccessJWT, auth)
+}
+
+// Tests that out of range refresh thresholds fall back to usable values.
+func TestJWTRefreshThresholdsLimits(t *testing.T) {
+	params := newClientOptionalParams(ServerBskySocial, "testHandle", "testAppKey", []clientOption{WithRefreshThresholds(-time.Minute, -time.Minute)})
+	assert.Equal(t, jwtAsyncRefreshThreshold, params.asyncRefreshThreshold)
+	assert.Equal(t, jwtSyncRefreshThreshold, params.syncRefreshThreshold)
+
+	params = newClientOptionalParams(ServerBskySocial, "testHandle", "testAppKey", []clientOption{WithRefreshThresholds(time.Minute, 10*time.Minute)})
+	assert.Equal(t, time.Minute, params.asyncRefreshThreshold)
+	assert.Equal(t, time.Minute, params.syncRefreshThreshold)
+}
+
+// Tests that failed refreshes and the final expiry of the session are reported
+// to the hooks.
+func TestJWTRefreshErrorHooks(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, now.Add(time.Minute)), getRefreshJwt(now, now.Add(time.Hour))))),
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 500,
+			Body:       io.NopCloser(strings.NewReader(`{"error": "InternalServerError"}`)),
+		}
+	}
+	refreshErrors := make(chan error, 1)
+	expired := make(chan error, 1)
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withRefreshRetryPause(time.Hour),
+		WithSessionHooks(SessionHooks{
+			OnRefreshError:   func(err error) { refreshErrors <- err },
+			OnSessionExpired: func(err error) { expired <- err },
+		}),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	// The access JWT is within the sync window right away, so the refresher
+	// tries and fails to refresh it before going back to sleep
+	select {
+	case err := <-refreshErrors:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("refresh error was not reported")
+	}
+	assert.True(t, c.Ready())
+
+	// Once the refresh token expired, the session is reported dead
+	clock.waitForTimers(t, 1)
+	clock.advance(time.Hour)
+	select {
+	case err := <-expired:
+		assert.ErrorIs(t, err, ErrSessionExpired)
+	case <-time.After(time.Second):
+		t.Fatalf("session expiry was not reported")
+	}
+	assert.False(t, c.Ready())
+}
+
 // Tests that if even the JWT refresh token got expired, the refresher errors
 // out synchronously.
 func TestJWTExpiredRefresh(t *testing.T) {
@@ -271,7 +610,6 @@ func TestJWTExpiredRefresh(t *testing.T) {
 
 	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
 		withClock(clock),
-		withJwtRefresherSleepFor(50*time.Millisecond),
 		withXrpcClient(&xrpc.Client{
 			Client: &http.Client{
 				Transport: mockTransport,
@@ -285,13 +623,230 @@ func TestJWTExpiredRefresh(t *testing.T) {
 
 	assert.True(t, c.Ready())
 
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.describeServer"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.createSession"], 1)
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.describeServer"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.createSession"), 1)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
 	time.Sleep(100 * time.Millisecond)
 
-	assert.Equal(t, mockTransport.calledMethods["/xrpc/com.atproto.server.refreshSession"], 0)
+	assert.Equal(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 0)
 
 	assert.False(t, c.Ready())
+
+	// The refresher is gone already, closing must not wait for it
+	c.Close()
+}
+
+// Tests that a client allowed to reauthenticate logs in again once its refresh
+// token expires, and reports the new login to the hook.
+func TestJWTExpiredRelogin(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+
+	now := clock.Now()
+	expiredSession := getCreateSessionResponse(getAccessJwt(now, now.Add(-10*time.Minute)), getRefreshJwt(now, now.Add(-2*time.Minute)))
+	freshSession := getCreateSessionResponse(getAccessJwt(now, now.Add(24*time.Hour)), getRefreshJwt(now, now.Add(72*time.Hour)))
+
+	mockTransport := newDefaultMockRoundTripper()
+	logins := 0
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.createSession"] = func(req *http.Request) *http.Response {
+		logins++
+		session := freshSession
+		if logins == 1 {
+			session = expiredSession
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(session)),
+		}
+	}
+	events := make(chan ReloginEvent, 1)
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		WithReauthentication(nil),
+		WithSessionHooks(SessionHooks{
+			OnRelogin: func(event ReloginEvent) { events <- event },
+		}),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Err)
+		assert.ErrorIs(t, event.Reason, ErrSessionExpired)
+		assert.Equal(t, "testHandle", event.Handle)
+		assert.Equal(t, "did:plc:test", event.Did)
+	case <-time.After(time.Second):
+		t.Fatalf("client did not log in again")
+	}
+	assert.True(t, c.Ready())
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+}
+
+// Tests that a client allowed to reauthenticate only logs in again if the server
+// rejects the refresh token itself, and only once per failed session.
+func TestJWTRefreshRejectedRelogin(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	mockTransport := newDefaultMockRoundTripper()
+	logins := 0
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.createSession"] = func(req *http.Request) *http.Response {
+		logins++
+		expire := now.Add(24 * time.Hour)
+		if logins == 1 {
+			expire = now.Add(time.Minute)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, expire), getRefreshJwt(now, now.Add(72*time.Hour))))),
+		}
+	}
+	refreshes := 0
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = func(req *http.Request) *http.Response {
+		refreshes++
+		reason := "InvalidRequest"
+		if refreshes > 1 {
+			reason = "ExpiredToken"
+		}
+		return &http.Response{
+			StatusCode: 400,
+			Body:       io.NopCloser(strings.NewReader(`{"error": "` + reason + `"}`)),
+		}
+	}
+	refreshErrors := make(chan error, 1)
+	events := make(chan ReloginEvent, 1)
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		WithReauthentication(nil),
+		WithSessionHooks(SessionHooks{
+			OnRefreshError: func(err error) { refreshErrors <- err },
+			OnRelogin:      func(event ReloginEvent) { events <- event },
+		}),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	// A refresh failing for any other reason is retried later, without a login
+	select {
+	case err := <-refreshErrors:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("refresh error was not reported")
+	}
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+
+	// A rejected refresh token logs in again, replacing the session that failed
+	impl := c.(*client)
+	failed := impl.session.Load()
+	clock.waitForTimers(t, 1)
+	clock.advance(30 * time.Second)
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Err)
+		assert.True(t, isTokenExpired(event.Reason))
+		assert.Equal(t, "did:plc:test", event.Did)
+	case <-time.After(time.Second):
+		t.Fatalf("client did not log in again")
+	}
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+	assert.NotSame(t, failed, impl.session.Load())
+
+	// Another failure reported for the same session doesn't log in again
+	assert.NoError(t, impl.relogin(context.Background(), failed, ErrSessionExpired))
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+
+	// The current session logs in exactly once, however often it fails
+	current := impl.session.Load()
+	assert.NoError(t, impl.relogin(context.Background(), current, ErrSessionExpired))
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Err)
+		assert.ErrorIs(t, event.Reason, ErrSessionExpired)
+	case <-time.After(time.Second):
+		t.Fatalf("relogin was not reported")
+	}
+	assert.NoError(t, impl.relogin(context.Background(), current, ErrSessionExpired))
+	assert.Equal(t, 3, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+	assert.Empty(t, events)
+}
+
+// Tests that calls rejected for an expired access token trigger a single session
+// refresh shared by all concurrent callers, after which each call is retried.
+func TestJWTReactiveRefresh(t *testing.T) {
+	var clock = &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	originalAccessJWT := getAccessJwt(now, now.Add(24*time.Hour))
+	postRefreshAccessJWT := getAccessJwt(now, now.Add(48*time.Hour))
+	refreshJWT := getRefreshJwt(now, now.Add(72*time.Hour))
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(originalAccessJWT, refreshJWT))),
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(getRefreshSessionResponse(postRefreshAccessJWT, refreshJWT))),
+		}
+	}
+	// The server revoked the original access token before its expiration
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.searchPosts"] = func(req *http.Request) *http.Response {
+		if req.Header.Get("Authorization") != "Bearer "+postRefreshAccessJWT {
+			return &http.Response{
+				StatusCode: 400,
+				Body:       io.NopCloser(strings.NewReader(`{"error": "ExpiredToken", "message": "Token has expired"}`)),
+			}
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"posts": []}`)),
+		}
+	}
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	var (
+		callers = 8
+		errc    = make(chan error, callers)
+	)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+			errc <- err
+		}()
+	}
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, <-errc)
+	}
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
 }
@@ -38,7 +38,7 @@ func TestBskySocialIntegration(t *testing.T) {
 	searchReq := SearchPostsRequest{}
 	searchReq.Q = "peterman"
 	searchReq.Limit = 10
-	out, err := realClient.SearchPosts(&searchReq)
+	out, err := realClient.SearchPosts(context.Background(), &searchReq)
 
 	if err != nil {
 		log.Err(err).Msg("failed query")
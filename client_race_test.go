@@ -0,0 +1,166 @@
+package bluesky
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests hammer the client from many goroutines while its session changes
+// in the background. They are meant to be run with -race.
+
+// Returns a mock transport whose sessions are always close to expiration, so a
+// client using it refreshes once per refresh retry pause.
+func newChurningMockRoundTripper(clock *mockClock) *mockRoundTripper {
+	shortSession := func() string {
+		now := clock.Now()
+		return getCreateSessionResponse(getAccessJwt(now, now.Add(3*time.Minute)), getRefreshJwt(now, now.Add(72*time.Hour)))
+	}
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.createSession"] = func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(shortSession()))}
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.server.refreshSession"] = func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(shortSession()))}
+	}
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.searchPosts"] = func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"posts": []}`))}
+	}
+	return mockTransport
+}
+
+func newChurningClient(t *testing.T, clock *mockClock, transport http.RoundTripper) Client {
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: transport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	return c
+}
+
+// Tests that API calls can run concurrently with continuous session refreshes.
+func TestRaceCallsDuringRefresh(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	mockTransport := newChurningMockRoundTripper(clock)
+
+	c := newChurningClient(t, clock, mockTransport)
+	defer c.Close()
+
+	// Keep calling until the session was replaced a good few times. Sessions
+	// are issued inside the refresh window, so every retry pause the clock is
+	// moved by triggers another refresh.
+	var (
+		wg       sync.WaitGroup
+		done     = make(chan struct{})
+		deadline = time.Now().Add(5 * time.Second)
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+				assert.NoError(t, err)
+				assert.True(t, c.Ready())
+			}
+		}()
+	}
+	for mockTransport.calls("/xrpc/com.atproto.server.refreshSession") < 10 && time.Now().Before(deadline) {
+		clock.advance(30 * time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"), 10)
+}
+
+// Tests that closing the client concurrently from many goroutines, while calls
+// and refreshes are in flight, returns promptly and leaves the client unusable.
+func TestRaceConcurrentClose(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	mockTransport := newChurningMockRoundTripper(clock)
+
+	c := newChurningClient(t, clock, mockTransport)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"}); err != nil {
+					assert.ErrorIs(t, err, ErrClientClosed)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			time.Sleep(5 * time.Millisecond)
+			assert.NoError(t, c.Close())
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("concurrent close hung")
+	}
+	assert.False(t, c.Ready())
+
+	_, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+// Tests that closing a client whose refresher already gave up on an expired
+// session doesn't hang.
+func TestRaceCloseAfterSessionExpired(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getCreateSessionResponse(getAccessJwt(now, now.Add(time.Hour)), getRefreshJwt(now, now.Add(2*time.Hour))))),
+	}
+	c := newChurningClient(t, clock, mockTransport)
+
+	// Expire the whole session, the refresher shuts down on its next tick
+	clock.advance(3 * time.Hour)
+	for c.Ready() {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close hung after the refresher exited")
+	}
+}
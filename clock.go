@@ -0,0 +1,44 @@
+package bluesky
+
+import "time"
+
+// Exposed for mocking clock to test jwt refresh semantics. All time related
+// operations of the client go through it, so tests can control time passage.
+type clockInterface interface {
+	Now() time.Time
+
+	// NewTimer creates a timer that fires once the clock advanced by d.
+	NewTimer(d time.Duration) clockTimer
+}
+
+// clockTimer is a single shot timer created by a clockInterface.
+type clockTimer interface {
+	// C returns the channel the current time is delivered on when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, returning false if it already did.
+	Stop() bool
+}
+
+type realClockImpl struct{}
+
+func (*realClockImpl) Now() time.Time {
+	return time.Now()
+}
+
+func (*realClockImpl) NewTimer(d time.Duration) clockTimer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+// realTimer is a clockTimer backed by a wall clock timer.
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
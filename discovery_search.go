@@ -0,0 +1,25 @@
+package bluesky
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+func (c *client) SearchStarterPacksIter(ctx context.Context, request *SearchStarterPacksRequest, maxResults int) *Iterator[*bsky.GraphDefs_StarterPackViewBasic] {
+	return newQueryIterator(ctx, request, maxResults, c.SearchStarterPacks,
+		func(req *SearchStarterPacksRequest) *string { return &req.Cursor },
+		func(out *GraphSearchStarterPacks_Output) ([]*bsky.GraphDefs_StarterPackViewBasic, *string) {
+			return out.StarterPacks, out.Cursor
+		},
+		func(pack *bsky.GraphDefs_StarterPackViewBasic) string { return pack.Uri })
+}
+
+func (c *client) GetPopularFeedGeneratorsIter(ctx context.Context, request *GetPopularFeedGeneratorsRequest, maxResults int) *Iterator[*bsky.FeedDefs_GeneratorView] {
+	return newQueryIterator(ctx, request, maxResults, c.GetPopularFeedGenerators,
+		func(req *GetPopularFeedGeneratorsRequest) *string { return &req.Cursor },
+		func(out *bsky.UnspeccedGetPopularFeedGenerators_Output) ([]*bsky.FeedDefs_GeneratorView, *string) {
+			return out.Feeds, out.Cursor
+		},
+		func(feed *bsky.FeedDefs_GeneratorView) string { return feed.Uri })
+}
@@ -0,0 +1,87 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getStarterPackStr(uri string) string {
+	return fmt.Sprintf(`{
+		"uri": "%s",
+		"cid": "bafyreic27io7r2mt3fng5nco7xrulxpfe63sto5urr7e6sfjrtwsm7tjke",
+		"creator": {"did": "did:plc:test", "handle": "test.bsky.social"},
+		"record": {"$type": "app.bsky.graph.starterpack", "createdAt": "2025-02-08T18:07:05Z", "list": "at://did:plc:test/app.bsky.graph.list/1", "name": "Pack"},
+		"indexedAt": "2025-02-08T18:07:05.920Z"
+	}`, uri)
+}
+
+func getFeedGeneratorStr(uri string) string {
+	return fmt.Sprintf(`{
+		"uri": "%s",
+		"cid": "bafyreic27io7r2mt3fng5nco7xrulxpfe63sto5urr7e6sfjrtwsm7tjke",
+		"did": "did:web:feeds.example.com",
+		"creator": {"did": "did:plc:test", "handle": "test.bsky.social"},
+		"displayName": "Feed",
+		"indexedAt": "2025-02-08T18:07:05.920Z"
+	}`, uri)
+}
+
+// Tests that the starter pack iterator follows cursors until they run out,
+// skipping packs that were already seen on a previous page.
+func TestSearchStarterPacksIter(t *testing.T) {
+	mockTransport := newPagesRoundTripper("app.bsky.graph.searchStarterPacks", map[string]string{
+		"":  fmt.Sprintf(`{"starterPacks": [%s, %s], "cursor": "2"}`, getStarterPackStr("at://a"), getStarterPackStr("at://b")),
+		"2": fmt.Sprintf(`{"starterPacks": [%s, %s]}`, getStarterPackStr("at://b"), getStarterPackStr("at://c")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.SearchStarterPacksIter(context.Background(), &SearchStarterPacksRequest{Q: "go"}, 0)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://c"}, uris)
+
+	// The query is required by the lexicon
+	_, err := c.SearchStarterPacks(context.Background(), &SearchStarterPacksRequest{})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/app.bsky.graph.searchStarterPacks"))
+}
+
+// Tests searching feed generators across pages, capped at a maximum.
+func TestGetPopularFeedGeneratorsIter(t *testing.T) {
+	var queries []string
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/app.bsky.unspecced.getPopularFeedGenerators"] = func(req *http.Request) *http.Response {
+		queries = append(queries, req.URL.RawQuery)
+		page := fmt.Sprintf(`{"feeds": [%s, %s], "cursor": "2"}`, getFeedGeneratorStr("at://a"), getFeedGeneratorStr("at://b"))
+		if req.URL.Query().Get("cursor") == "2" {
+			page = fmt.Sprintf(`{"feeds": [%s, %s], "cursor": "3"}`, getFeedGeneratorStr("at://c"), getFeedGeneratorStr("at://d"))
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(page)),
+		}
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.GetPopularFeedGeneratorsIter(context.Background(), &GetPopularFeedGeneratorsRequest{Query: "cats", Limit: 2}, 3)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://c"}, uris)
+	assert.Equal(t, []string{"limit=2&query=cats", "cursor=2&limit=2&query=cats"}, queries)
+}
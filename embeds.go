@@ -0,0 +1,170 @@
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+)
+
+// Limits of the app.bsky.embed lexicons. Anything beyond them, such as the
+// size and length limits a PDS applies to uploaded videos, is left to the
+// server.
+const (
+	// embedMaxImages is the most images a post may embed.
+	embedMaxImages = 4
+
+	// imageMaxSize is the largest image or link card thumbnail a post may
+	// embed, in bytes.
+	imageMaxSize = 1_000_000
+
+	// videoMaxSize is the largest video a post may embed, in bytes.
+	videoMaxSize = 100_000_000
+
+	// videoMimeType is the only video format posts may embed.
+	videoMimeType = "video/mp4"
+)
+
+// Image is an image to embed in a post.
+type Image struct {
+	Data     io.Reader // Encoded image, at most 1MB
+	MimeType string    // e.g. "image/jpeg", detected from the data if empty
+	Alt      string    // Alt text for accessibility
+	Width    int       // Width in pixels, detected for JPEG, PNG and GIF if unset
+	Height   int       // Height in pixels, detected for JPEG, PNG and GIF if unset
+}
+
+// Video is a video to embed in a post.
+type Video struct {
+	Data     io.Reader // Encoded MP4 video, at most 100MB
+	MimeType string    // Defaults to "video/mp4"
+	Alt      string    // Alt text for accessibility
+	Width    int       // Width in pixels, optional
+	Height   int       // Height in pixels, optional
+}
+
+func (c *client) ImagesEmbed(ctx context.Context, images ...*Image) (*bsky.FeedPost_Embed, error) {
+	verr := &ValidationError{Method: "com.atproto.repo.createRecord"}
+	if len(images) == 0 || len(images) > embedMaxImages {
+		verr.Fields = append(verr.Fields, FieldError{Field: "images", Param: "images", Value: len(images), Reason: fmt.Sprintf("must have between 1 and %d elements", embedMaxImages)})
+		return nil, verr
+	}
+	// Check all images before uploading any of them
+	data := make([][]byte, len(images))
+	mimeTypes := make([]string, len(images))
+	for i, img := range images {
+		if img == nil {
+			verr.Fields = append(verr.Fields, FieldError{Field: "images", Param: "images", Value: nil, Reason: fmt.Sprintf("element %d is required", i)})
+			continue
+		}
+		var err error
+		if data[i], err = readBlob(img.Data, imageMaxSize); err != nil {
+			return nil, err
+		}
+		var reason string
+		if mimeTypes[i], reason = checkBlob(data[i], img.MimeType, "image/", imageMaxSize); reason != "" {
+			verr.Fields = append(verr.Fields, FieldError{Field: "images", Param: "images", Value: mimeTypes[i], Reason: fmt.Sprintf("element %d %s", i, reason)})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+
+	embed := &bsky.EmbedImages{LexiconTypeID: "app.bsky.embed.images"}
+	for i, img := range images {
+		blob, err := c.uploadBlob(ctx, data[i], mimeTypes[i])
+		if err != nil {
+			return nil, err
+		}
+		embed.Images = append(embed.Images, &bsky.EmbedImages_Image{
+			Alt:         img.Alt,
+			AspectRatio: aspectRatio(img.Width, img.Height, data[i]),
+			Image:       blob,
+		})
+	}
+	return &bsky.FeedPost_Embed{EmbedImages: embed}, nil
+}
+
+func (c *client) ExternalEmbed(ctx context.Context, uri string, title string, description string, thumb *Image) (*bsky.FeedPost_Embed, error) {
+	if err := checkFormat("uri", uri); err != nil {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: fmt.Sprintf("must be a valid uri: %v", err)}}}
+	}
+	external := &bsky.EmbedExternal_External{
+		Uri:         uri,
+		Title:       title,
+		Description: description,
+	}
+	if thumb != nil {
+		blob, err := c.uploadImage(ctx, thumb)
+		if err != nil {
+			return nil, err
+		}
+		external.Thumb = blob
+	}
+	return &bsky.FeedPost_Embed{EmbedExternal: &bsky.EmbedExternal{LexiconTypeID: "app.bsky.embed.external", External: external}}, nil
+}
+
+func (c *client) VideoEmbed(ctx context.Context, video *Video) (*bsky.FeedPost_Embed, error) {
+	if video == nil {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "video", Param: "video", Value: nil, Reason: "is required"}}}
+	}
+	data, err := readBlob(video.Data, videoMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := video.MimeType
+	if mimeType == "" {
+		mimeType = videoMimeType
+	}
+	mimeType, reason := checkBlob(data, mimeType, videoMimeType, videoMaxSize)
+	if reason != "" {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "video", Param: "video", Value: mimeType, Reason: reason}}}
+	}
+	blob, err := c.uploadBlob(ctx, data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	embed := &bsky.EmbedVideo{
+		LexiconTypeID: "app.bsky.embed.video",
+		AspectRatio:   aspectRatio(video.Width, video.Height, nil),
+		Video:         blob,
+	}
+	if video.Alt != "" {
+		embed.Alt = &video.Alt
+	}
+	return &bsky.FeedPost_Embed{EmbedVideo: embed}, nil
+}
+
+// uploadImage checks and uploads a single link card thumbnail.
+func (c *client) uploadImage(ctx context.Context, img *Image) (*util.LexBlob, error) {
+	data, err := readBlob(img.Data, imageMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, reason := checkBlob(data, img.MimeType, "image/", imageMaxSize)
+	if reason != "" {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "thumb", Param: "thumb", Value: mimeType, Reason: reason}}}
+	}
+	return c.uploadBlob(ctx, data, mimeType)
+}
+
+// aspectRatio returns the given dimensions, falling back to the ones decoded
+// from the image data. It returns nil if neither is available.
+func aspectRatio(width int, height int, data []byte) *bsky.EmbedDefs_AspectRatio {
+	if (width <= 0 || height <= 0) && data != nil {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			width, height = config.Width, config.Height
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	return &bsky.EmbedDefs_AspectRatio{Width: int64(width), Height: int64(height)}
+}
@@ -0,0 +1,147 @@
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a mock transport accepting blob uploads, reporting the MIME type and
+// size of each upload back in the blob.
+func newUploadBlobRoundTripper() *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.uploadBlob"] = func(req *http.Request) *http.Response {
+		data, _ := io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: 200,
+			Body: io.NopCloser(strings.NewReader(fmt.Sprintf(`{"blob": {
+				"$type": "blob",
+				"ref": {"$link": "bafkreih4rixyzlfmlmgz3w2qvmvocdydzy5jrvzpf5toqf2uyldrtrcx7e"},
+				"mimeType": "%s",
+				"size": %d
+			}}`, req.Header.Get("Content-Type"), len(data)))),
+		}
+	}
+	return mockTransport
+}
+
+func getPNG(width int, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+// Tests uploading blobs, sniffing the MIME type if none is given.
+func TestUploadBlob(t *testing.T) {
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	blob, err := c.UploadBlob(context.Background(), strings.NewReader("hello"), "text/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", blob.MimeType)
+	assert.Equal(t, int64(5), blob.Size)
+
+	blob, err = c.UploadBlob(context.Background(), bytes.NewReader(getPNG(1, 1)), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", blob.MimeType)
+
+	_, err = c.UploadBlob(context.Background(), strings.NewReader(""), "text/plain")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.repo.uploadBlob"))
+}
+
+// Tests embedding images, detecting their aspect ratio unless given.
+func TestImagesEmbed(t *testing.T) {
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	embed, err := c.ImagesEmbed(context.Background(),
+		&Image{Data: bytes.NewReader(getPNG(40, 30)), Alt: "detected"},
+		&Image{Data: bytes.NewReader(getPNG(40, 30)), MimeType: "image/png", Alt: "given", Width: 4000, Height: 3000},
+		&Image{Data: strings.NewReader("not really a webp"), MimeType: "image/webp", Alt: "unknown"},
+	)
+	assert.NoError(t, err)
+
+	raw, err := json.Marshal(embed)
+	assert.NoError(t, err)
+	var out struct {
+		Type   string `json:"$type"`
+		Images []struct {
+			Alt         string         `json:"alt"`
+			AspectRatio map[string]int `json:"aspectRatio"`
+			Image       struct {
+				MimeType string `json:"mimeType"`
+			} `json:"image"`
+		} `json:"images"`
+	}
+	assert.NoError(t, json.Unmarshal(raw, &out))
+	assert.Equal(t, "app.bsky.embed.images", out.Type)
+	assert.Len(t, out.Images, 3)
+	assert.Equal(t, "detected", out.Images[0].Alt)
+	assert.Equal(t, map[string]int{"width": 40, "height": 30}, out.Images[0].AspectRatio)
+	assert.Equal(t, "image/png", out.Images[0].Image.MimeType)
+	assert.Equal(t, map[string]int{"width": 4000, "height": 3000}, out.Images[1].AspectRatio)
+	assert.Nil(t, out.Images[2].AspectRatio)
+	assert.Equal(t, "image/webp", out.Images[2].Image.MimeType)
+}
+
+// Tests that no image is uploaded if any of them violates the limits.
+func TestImagesEmbedValidation(t *testing.T) {
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	_, err := c.ImagesEmbed(context.Background(),
+		&Image{Data: bytes.NewReader(getPNG(1, 1))},
+		&Image{Data: bytes.NewReader(make([]byte, imageMaxSize+1)), MimeType: "image/jpeg"},
+		&Image{Data: strings.NewReader("text"), MimeType: "text/plain"},
+		nil,
+	)
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Len(t, verr.Fields, 3)
+		assert.Contains(t, verr.Fields[2].Reason, "element 3 is required")
+	}
+
+	_, err = c.ImagesEmbed(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.repo.uploadBlob"))
+}
+
+// Tests embedding link cards and videos.
+func TestExternalAndVideoEmbed(t *testing.T) {
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	embed, err := c.ExternalEmbed(context.Background(), "https://example.com", "Title", "Description", &Image{Data: bytes.NewReader(getPNG(1, 1))})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", embed.EmbedExternal.External.Uri)
+	assert.Equal(t, "image/png", embed.EmbedExternal.External.Thumb.MimeType)
+
+	_, err = c.ExternalEmbed(context.Background(), "not a uri", "Title", "Description", nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	embed, err = c.VideoEmbed(context.Background(), &Video{Data: strings.NewReader("fake mp4"), Alt: "clip", Width: 16, Height: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, "video/mp4", embed.EmbedVideo.Video.MimeType)
+	assert.Equal(t, "clip", *embed.EmbedVideo.Alt)
+	assert.Equal(t, int64(16), embed.EmbedVideo.AspectRatio.Width)
+
+	_, err = c.VideoEmbed(context.Background(), &Video{Data: strings.NewReader("fake mov"), MimeType: "video/quicktime"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = c.VideoEmbed(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.repo.uploadBlob"))
+}
@@ -0,0 +1,153 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// likeCollection is the NSID of like records.
+	likeCollection = "app.bsky.feed.like"
+
+	// repostCollection is the NSID of repost records.
+	repostCollection = "app.bsky.feed.repost"
+)
+
+func (c *client) Like(ctx context.Context, uri string) (*atproto.RepoStrongRef, error) {
+	post, err := c.getPost(ctx, "com.atproto.repo.createRecord", uri)
+	if err != nil {
+		return nil, err
+	}
+	if post.Viewer != nil && post.Viewer.Like != nil {
+		return c.existingRecord(ctx, *post.Viewer.Like)
+	}
+	return c.engage(ctx, likeCollection, &bsky.FeedLike{
+		LexiconTypeID: likeCollection,
+		Subject:       &atproto.RepoStrongRef{Uri: post.Uri, Cid: post.Cid},
+		CreatedAt:     c.clock.Now().UTC().Format(syntax.AtprotoDatetimeLayout),
+	})
+}
+
+func (c *client) Unlike(ctx context.Context, uri string) error {
+	post, err := c.getPost(ctx, "com.atproto.repo.deleteRecord", uri)
+	if err != nil {
+		return err
+	}
+	if post.Viewer == nil || post.Viewer.Like == nil {
+		return nil
+	}
+	return c.DeleteRecord(ctx, *post.Viewer.Like)
+}
+
+func (c *client) Repost(ctx context.Context, uri string) (*atproto.RepoStrongRef, error) {
+	post, err := c.getPost(ctx, "com.atproto.repo.createRecord", uri)
+	if err != nil {
+		return nil, err
+	}
+	if post.Viewer != nil && post.Viewer.Repost != nil {
+		return c.existingRecord(ctx, *post.Viewer.Repost)
+	}
+	return c.engage(ctx, repostCollection, &bsky.FeedRepost{
+		LexiconTypeID: repostCollection,
+		Subject:       &atproto.RepoStrongRef{Uri: post.Uri, Cid: post.Cid},
+		CreatedAt:     c.clock.Now().UTC().Format(syntax.AtprotoDatetimeLayout),
+	})
+}
+
+func (c *client) Unrepost(ctx context.Context, uri string) error {
+	post, err := c.getPost(ctx, "com.atproto.repo.deleteRecord", uri)
+	if err != nil {
+		return err
+	}
+	if post.Viewer == nil || post.Viewer.Repost == nil {
+		return nil
+	}
+	return c.DeleteRecord(ctx, *post.Viewer.Repost)
+}
+
+func (c *client) Quote(ctx context.Context, uri string, post *Post) (*atproto.RepoStrongRef, error) {
+	quoted, err := c.getPost(ctx, "com.atproto.repo.createRecord", uri)
+	if err != nil {
+		return nil, err
+	}
+	if quoted.Viewer != nil && quoted.Viewer.EmbeddingDisabled != nil && *quoted.Viewer.EmbeddingDisabled {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: "must point to a post which allows quoting"}}}
+	}
+	record := &bsky.EmbedRecord{
+		LexiconTypeID: "app.bsky.embed.record",
+		Record:        &atproto.RepoStrongRef{Uri: quoted.Uri, Cid: quoted.Cid},
+	}
+	// Work on a copy so that the caller's post keeps its own embed
+	var quote Post
+	if post != nil {
+		quote = *post
+	}
+	switch {
+	case quote.Embed == nil:
+		quote.Embed = &bsky.FeedPost_Embed{EmbedRecord: record}
+	case quote.Embed.EmbedImages != nil || quote.Embed.EmbedVideo != nil || quote.Embed.EmbedExternal != nil:
+		quote.Embed = &bsky.FeedPost_Embed{EmbedRecordWithMedia: &bsky.EmbedRecordWithMedia{
+			LexiconTypeID: "app.bsky.embed.recordWithMedia",
+			Record:        record,
+			Media: &bsky.EmbedRecordWithMedia_Media{
+				EmbedImages:   quote.Embed.EmbedImages,
+				EmbedVideo:    quote.Embed.EmbedVideo,
+				EmbedExternal: quote.Embed.EmbedExternal,
+			},
+		}}
+	default:
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "Embed", Param: "embed", Value: quote.Embed, Reason: "must be images, a video or a link card when quoting"}}}
+	}
+	return c.createPost(ctx, &quote, nil)
+}
+
+// getPost fetches the view of a post, with its CID and the logged in account's
+// likes and reposts of it. Malformed AT-URIs are reported for the given method.
+func (c *client) getPost(ctx context.Context, method string, uri string) (*bsky.FeedDefs_PostView, error) {
+	if _, err := parseRecordURI(method, uri, postCollection); err != nil {
+		return nil, err
+	}
+	var out bsky.FeedGetPosts_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.feed.getPosts", map[string]interface{}{"uris": []string{uri}}, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to get post.")
+		return nil, err
+	}
+	if len(out.Posts) == 0 {
+		return nil, fmt.Errorf("post not found: %s", uri)
+	}
+	return out.Posts[0], nil
+}
+
+// engage stores a like or repost in the client's repository.
+func (c *client) engage(ctx context.Context, collection string, record util.CBOR) (*atproto.RepoStrongRef, error) {
+	ref, err := c.createRecord(ctx, collection, record)
+	if err != nil {
+		log.Err(err).Msgf("Failed to create %s record.", collection)
+		return nil, err
+	}
+	return ref, nil
+}
+
+// existingRecord returns the strong reference of a record the viewer state of
+// a post points to.
+func (c *client) existingRecord(ctx context.Context, uri string) (*atproto.RepoStrongRef, error) {
+	parsed, err := syntax.ParseATURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.getRecord(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+	if out.Cid == nil {
+		return nil, fmt.Errorf("record has no CID: %s", uri)
+	}
+	return &atproto.RepoStrongRef{Uri: out.Uri, Cid: *out.Cid}, nil
+}
@@ -0,0 +1,161 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPostUri is the AT-URI of the post served by newEngagementRoundTripper.
+const testPostUri = "at://did:plc:other/app.bsky.feed.post/3l4m5n6o7p8q9"
+
+// Returns a mock transport serving a post by another account, whose viewer
+// state reflects the likes and reposts of it in records.
+func newEngagementRoundTripper(records map[string]string, embeddingDisabled bool) *mockRoundTripper {
+	mockTransport := newRecordsRoundTripper(records)
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.getPosts"] = func(req *http.Request) *http.Response {
+		if req.URL.Query().Get("uris") != testPostUri {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"posts": []}`)),
+			}
+		}
+		viewer := map[string]interface{}{"embeddingDisabled": embeddingDisabled}
+		for uri, record := range records {
+			var value struct {
+				Type    string `json:"$type"`
+				Subject struct {
+					Uri string `json:"uri"`
+				} `json:"subject"`
+			}
+			json.Unmarshal([]byte(record), &value)
+			if value.Subject.Uri != testPostUri {
+				continue
+			}
+			switch value.Type {
+			case likeCollection:
+				viewer["like"] = uri
+			case repostCollection:
+				viewer["repost"] = uri
+			}
+		}
+		view, _ := json.Marshal(map[string]interface{}{
+			"uri":       testPostUri,
+			"cid":       "cid-post",
+			"author":    map[string]string{"did": "did:plc:other", "handle": "other.bsky.social"},
+			"record":    map[string]string{"$type": postCollection, "text": "hello", "createdAt": "2025-02-08T18:07:05Z"},
+			"indexedAt": "2025-02-08T18:07:05Z",
+			"viewer":    viewer,
+		})
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"posts": [` + string(view) + `]}`)),
+		}
+	}
+	return mockTransport
+}
+
+// Tests liking and unliking a post, neither of which is repeated.
+func TestLikeUnlike(t *testing.T) {
+	records := make(map[string]string)
+	mockTransport := newEngagementRoundTripper(records, false)
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	like, err := c.Like(context.Background(), testPostUri)
+	assert.NoError(t, err)
+	assert.Contains(t, like.Uri, "at://did:plc:test/app.bsky.feed.like/")
+
+	record, err := GetRecord[*bsky.FeedLike](context.Background(), c, like.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, testPostUri, record.Value.Subject.Uri)
+	assert.Equal(t, "cid-post", record.Value.Subject.Cid)
+
+	again, err := c.Like(context.Background(), testPostUri)
+	assert.NoError(t, err)
+	assert.Equal(t, like, again)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.repo.createRecord"))
+
+	assert.NoError(t, c.Unlike(context.Background(), testPostUri))
+	assert.Empty(t, records)
+	assert.NoError(t, c.Unlike(context.Background(), testPostUri))
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.repo.deleteRecord"))
+
+	_, err = c.Like(context.Background(), "at://did:plc:other/app.bsky.feed.like/3l4m5n6o7p8q9")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = c.Like(context.Background(), "at://did:plc:other/app.bsky.feed.post/missing")
+	assert.Error(t, err)
+}
+
+// Tests reposting and unreposting a post.
+func TestRepostUnrepost(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newEngagementRoundTripper(records, false))
+	defer c.Close()
+
+	repost, err := c.Repost(context.Background(), testPostUri)
+	assert.NoError(t, err)
+	record, err := GetRecord[*bsky.FeedRepost](context.Background(), c, repost.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, testPostUri, record.Value.Subject.Uri)
+
+	// Likes and reposts are undone independently
+	_, err = c.Like(context.Background(), testPostUri)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Unrepost(context.Background(), testPostUri))
+	assert.Len(t, records, 1)
+	assert.NotContains(t, records, repost.Uri)
+}
+
+// Tests quoting posts, with and without media.
+func TestQuote(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newEngagementRoundTripper(records, false))
+	defer c.Close()
+
+	ref, err := c.Quote(context.Background(), testPostUri, &Post{Text: "look at this"})
+	assert.NoError(t, err)
+	quote, err := GetRecord[*bsky.FeedPost](context.Background(), c, ref.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "look at this", quote.Value.Text)
+	if assert.NotNil(t, quote.Value.Embed.EmbedRecord) {
+		assert.Equal(t, testPostUri, quote.Value.Embed.EmbedRecord.Record.Uri)
+		assert.Equal(t, "cid-post", quote.Value.Embed.EmbedRecord.Record.Cid)
+	}
+
+	external := &bsky.FeedPost_Embed{EmbedExternal: &bsky.EmbedExternal{
+		LexiconTypeID: "app.bsky.embed.external",
+		External:      &bsky.EmbedExternal_External{Uri: "https://example.com", Title: "Example"},
+	}}
+	post := &Post{Text: "with a card", Embed: external}
+	ref, err = c.Quote(context.Background(), testPostUri, post)
+	assert.NoError(t, err)
+	assert.Equal(t, external, post.Embed)
+	quote, err = GetRecord[*bsky.FeedPost](context.Background(), c, ref.Uri)
+	assert.NoError(t, err)
+	if assert.NotNil(t, quote.Value.Embed.EmbedRecordWithMedia) {
+		assert.Equal(t, testPostUri, quote.Value.Embed.EmbedRecordWithMedia.Record.Record.Uri)
+		assert.Equal(t, "Example", quote.Value.Embed.EmbedRecordWithMedia.Media.EmbedExternal.External.Title)
+	}
+
+	// Quoting a quote's embed is not possible
+	_, err = c.Quote(context.Background(), testPostUri, &Post{Text: "nested", Embed: quote.Value.Embed})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// Tests that posts disallowing quotes are not quoted.
+func TestQuoteDisabled(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newEngagementRoundTripper(records, true))
+	defer c.Close()
+
+	_, err := c.Quote(context.Background(), testPostUri, &Post{Text: "look at this"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,60 @@
+package bluesky
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+)
+
+// Filters of GetAuthorFeedRequest, selecting which of an author's posts and
+// reposts are returned. Servers may support filters beyond these.
+const (
+	AuthorFeedPostsWithReplies      = "posts_with_replies"       // Everything, the default
+	AuthorFeedPostsNoReplies        = "posts_no_replies"         // Posts and reposts, but no replies
+	AuthorFeedPostsWithMedia        = "posts_with_media"         // Posts with images or videos
+	AuthorFeedPostsAndAuthorThreads = "posts_and_author_threads" // Posts and replies within the author's own threads
+	AuthorFeedPostsWithVideo        = "posts_with_video"         // Posts with videos
+)
+
+func (c *client) GetTimelineIter(ctx context.Context, request *GetTimelineRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost] {
+	return newQueryIterator(ctx, request, maxResults, c.GetTimeline,
+		func(req *GetTimelineRequest) *string { return &req.Cursor },
+		func(out *bsky.FeedGetTimeline_Output) ([]*bsky.FeedDefs_FeedViewPost, *string) {
+			return out.Feed, out.Cursor
+		},
+		feedItemKey)
+}
+
+func (c *client) GetAuthorFeedIter(ctx context.Context, request *GetAuthorFeedRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost] {
+	return newQueryIterator(ctx, request, maxResults, c.GetAuthorFeed,
+		func(req *GetAuthorFeedRequest) *string { return &req.Cursor },
+		func(out *bsky.FeedGetAuthorFeed_Output) ([]*bsky.FeedDefs_FeedViewPost, *string) {
+			return out.Feed, out.Cursor
+		},
+		feedItemKey)
+}
+
+func (c *client) GetFeedIter(ctx context.Context, request *GetFeedRequest, maxResults int) *Iterator[*bsky.FeedDefs_FeedViewPost] {
+	return newQueryIterator(ctx, request, maxResults, c.GetFeed,
+		func(req *GetFeedRequest) *string { return &req.Cursor },
+		func(out *bsky.FeedGetFeed_Output) ([]*bsky.FeedDefs_FeedViewPost, *string) {
+			return out.Feed, out.Cursor
+		},
+		feedItemKey)
+}
+
+// feedItemKey identifies an item of a feed. The same post may show up more than
+// once when reposted by different accounts, or pinned, so the reason it shows
+// up is part of its identity.
+func feedItemKey(item *bsky.FeedDefs_FeedViewPost) string {
+	key := item.Post.Uri
+	if item.Reason != nil {
+		switch {
+		case item.Reason.FeedDefs_ReasonRepost != nil && item.Reason.FeedDefs_ReasonRepost.By != nil:
+			key += " repost " + item.Reason.FeedDefs_ReasonRepost.By.Did
+		case item.Reason.FeedDefs_ReasonPin != nil:
+			key += " pin"
+		}
+	}
+	return key
+}
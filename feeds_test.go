@@ -0,0 +1,114 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a feed item of the post at uri, reposted by the given DID unless it
+// is empty.
+func getFeedItemStr(uri string, repostedBy string) string {
+	if repostedBy == "" {
+		return fmt.Sprintf(`{"post": %s}`, getPostStr(uri))
+	}
+	return fmt.Sprintf(`{
+		"post": %s,
+		"reason": {
+			"$type": "app.bsky.feed.defs#reasonRepost",
+			"by": {"did": "%s", "handle": "reposter.bsky.social"},
+			"indexedAt": "2025-02-08T18:07:05.920Z"
+		}
+	}`, getPostStr(uri), repostedBy)
+}
+
+// Tests that the timeline iterator follows cursors, keeping posts showing up
+// again as reposts by someone else.
+func TestGetTimelineIter(t *testing.T) {
+	mockTransport := newPagesRoundTripper("app.bsky.feed.getTimeline", map[string]string{
+		"":  fmt.Sprintf(`{"feed": [%s, %s], "cursor": "2"}`, getFeedItemStr("at://a", ""), getFeedItemStr("at://b", "")),
+		"2": fmt.Sprintf(`{"feed": [%s, %s, %s]}`, getFeedItemStr("at://b", ""), getFeedItemStr("at://a", "did:plc:friend"), getFeedItemStr("at://c", "")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.GetTimelineIter(context.Background(), nil, 0)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Post.Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://a", "at://c"}, uris)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/app.bsky.feed.getTimeline"))
+}
+
+// Tests reading an author's feed with a filter, capped at a maximum.
+func TestGetAuthorFeedIter(t *testing.T) {
+	var queries []string
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.getAuthorFeed"] = func(req *http.Request) *http.Response {
+		queries = append(queries, req.URL.RawQuery)
+		page := fmt.Sprintf(`{"feed": [%s, %s], "cursor": "2"}`, getFeedItemStr("at://a", ""), getFeedItemStr("at://b", ""))
+		if req.URL.Query().Get("cursor") == "2" {
+			page = fmt.Sprintf(`{"feed": [%s, %s], "cursor": "3"}`, getFeedItemStr("at://c", ""), getFeedItemStr("at://d", ""))
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(page)),
+		}
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	request := &GetAuthorFeedRequest{Actor: "test.bsky.social", Filter: AuthorFeedPostsNoReplies, Limit: 2}
+	it := c.GetAuthorFeedIter(context.Background(), request, 3)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Post.Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://c"}, uris)
+	assert.Equal(t, []string{
+		"actor=test.bsky.social&filter=posts_no_replies&limit=2",
+		"actor=test.bsky.social&cursor=2&filter=posts_no_replies&limit=2",
+	}, queries)
+	assert.Empty(t, request.Cursor)
+
+	// Filters are open-ended and left to the server, missing actors are rejected
+	// before calling it
+	_, err := c.GetAuthorFeed(context.Background(), &GetAuthorFeedRequest{Actor: "test.bsky.social", Filter: "posts_with_cats"})
+	assert.NoError(t, err)
+	_, err = c.GetAuthorFeed(context.Background(), &GetAuthorFeedRequest{})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Len(t, queries, 3)
+}
+
+// Tests reading a custom feed, which must be given by AT-URI.
+func TestGetFeedIter(t *testing.T) {
+	mockTransport := newPagesRoundTripper("app.bsky.feed.getFeed", map[string]string{
+		"": fmt.Sprintf(`{"feed": [%s, %s]}`, getFeedItemStr("at://a", ""), getFeedItemStr("at://b", "")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.GetFeedIter(context.Background(), &GetFeedRequest{Feed: "at://did:plc:test/app.bsky.feed.generator/cats"}, 0)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Post.Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b"}, uris)
+
+	it = c.GetFeedIter(context.Background(), &GetFeedRequest{Feed: "https://bsky.app/profile/test/feed/cats"}, 0)
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), ErrInvalidRequest)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.feed.getFeed"))
+}
@@ -6,8 +6,10 @@ toolchain go1.22.9
 
 require (
 	github.com/bluesky-social/indigo v0.0.0-20241122170530-feceb364ee49
+	github.com/rivo/uniseg v0.4.7
 	github.com/rs/zerolog v1.33.0
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.23.0
 )
 
 require (
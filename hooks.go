@@ -0,0 +1,84 @@
+package bluesky
+
+import (
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// SessionHooks are callbacks the client invokes on changes to its session, e.g.
+// for persisting tokens, exporting metrics or auditing. Hooks are called
+// synchronously from the goroutine managing the session, so they must not
+// block. Unset hooks are skipped.
+type SessionHooks struct {
+	// OnRelogin is called after the client tried to replace its expired session
+	// with a new login, whether it succeeded or not.
+	OnRelogin func(event ReloginEvent)
+
+	// OnSessionRefreshed is called whenever the client obtained new tokens,
+	// either by refreshing its session or by logging in again.
+	OnSessionRefreshed func(event SessionRefreshedEvent)
+
+	// OnSessionExpired is called when the session expired for good and the client
+	// stopped refreshing it. A new client is needed to continue.
+	OnSessionExpired func(err error)
+
+	// OnRefreshError is called whenever an attempt to refresh the session failed.
+	// The client retries transient failures on its own.
+	OnRefreshError func(err error)
+}
+
+// ReloginEvent describes an attempt of the client to log in again after its
+// session could not be refreshed anymore.
+type ReloginEvent struct {
+	Handle string // Handle the client attempted to log in with
+	Did    string // DID of the new session, empty if the login failed
+	Reason error  // Reason why the previous session could not be refreshed
+	Err    error  // Failure of the login attempt, nil if it succeeded
+}
+
+// SessionRefreshedEvent describes the new session of the client.
+type SessionRefreshedEvent struct {
+	Auth             xrpc.AuthInfo // New credentials of the session
+	AccessJwtExpire  time.Time     // Expiration time of the new access JWT token
+	RefreshJwtExpire time.Time     // Expiration time of the new refresh JWT token
+}
+
+// WithSessionHooks registers callbacks to be notified of session changes.
+func WithSessionHooks(hooks SessionHooks) clientOption {
+	return func(params *clientOptionalParams) {
+		params.hooks = hooks
+	}
+}
+
+// notifyRelogin invokes the relogin hook if one was registered.
+func (c *client) notifyRelogin(event ReloginEvent) {
+	if c.hooks.OnRelogin != nil {
+		c.hooks.OnRelogin(event)
+	}
+}
+
+// notifySessionRefreshed invokes the session refreshed hook if one was registered.
+func (c *client) notifySessionRefreshed(sess *session) {
+	if c.hooks.OnSessionRefreshed != nil {
+		c.hooks.OnSessionRefreshed(SessionRefreshedEvent{
+			Auth:             *sess.auth,
+			AccessJwtExpire:  sess.accessJwtExpire,
+			RefreshJwtExpire: sess.refreshJwtExpire,
+		})
+	}
+}
+
+// notifySessionExpired invokes the session expired hook if one was registered.
+func (c *client) notifySessionExpired(err error) {
+	if c.hooks.OnSessionExpired != nil {
+		c.hooks.OnSessionExpired(err)
+	}
+}
+
+// notifyRefreshError invokes the refresh error hook if one was registered.
+func (c *client) notifyRefreshError(err error) {
+	if c.hooks.OnRefreshError != nil {
+		c.hooks.OnRefreshError(err)
+	}
+}
@@ -0,0 +1,548 @@
+// Command genrequests generates the request structs and client methods of XRPC
+// queries from their lexicon schemas.
+//
+// Usage:
+//
+//	genrequests [-lexicons dir] [-out file] [-datetime nsid.param,...] [-enum nsid.param=a|b,...] [-output nsid,...] nsid[=Method]...
+//
+// For every NSID, the schema is read from the lexicons directory (e.g.
+// lexicons/app/bsky/feed/searchPosts.json) and turned into a request struct
+// whose `xrpc` tags carry the parameter constraints, along with a client method
+// validating and sending it. The method is named after the last NSID segment
+// unless overridden. Only lexicon enums are enforced, known values are open-ended
+// unless -enum restricts them. Responses are decoded into indigo's types, except
+// for the NSIDs passed to -output whose output types are generated too.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// schema is the subset of a lexicon schema file needed to generate requests.
+type schema struct {
+	ID   string                 `json:"id"`
+	Defs map[string]*typeSchema `json:"defs"`
+}
+
+// typeSchema is the subset of a lexicon type definition needed to generate requests.
+type typeSchema struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Format      string                 `json:"format"`
+	Parameters  *typeSchema            `json:"parameters"`
+	Output      *bodySchema            `json:"output"`
+	Required    []string               `json:"required"`
+	Properties  map[string]*typeSchema `json:"properties"`
+	Items       *typeSchema            `json:"items"`
+	Ref         string                 `json:"ref"`
+	Enum        []string               `json:"enum"`
+	KnownValues []string               `json:"knownValues"`
+	Default     any                    `json:"default"`
+	Minimum     *int64                 `json:"minimum"`
+	Maximum     *int64                 `json:"maximum"`
+	MaxLength   *int                   `json:"maxLength"`
+}
+
+// bodySchema describes the output of a query.
+type bodySchema struct {
+	Encoding string      `json:"encoding"`
+	Schema   *typeSchema `json:"schema"`
+}
+
+// endpoint is the template input for a single query.
+type endpoint struct {
+	NSID        string
+	Method      string
+	Request     string
+	Output      string
+	Description []string
+	Params      []param
+	Fields      []field // Fields of the output type, if it is generated too
+}
+
+// field is the template input for a single field of a generated output type.
+type field struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+// param is the template input for a single request field.
+type param struct {
+	Field       string
+	Type        string
+	Tag         string
+	Description []string
+}
+
+// packages maps lexicon namespaces to the indigo packages holding their types.
+var packages = map[string]string{
+	"app.bsky":    "bsky",
+	"com.atproto": "atproto",
+	"chat.bsky":   "chat",
+	"tools.ozone": "ozone",
+}
+
+// options are the command line arguments of the generator.
+type options struct {
+	lexicons  string   // Directory holding the lexicon schemas
+	out       string   // File to write the generated code to
+	datetimes []string // nsid.param string parameters to expose as time.Time
+	enums     []string // nsid.param=a|b string parameters restricted to the given values
+	outputs   []string // NSIDs whose output types are generated as indigo lacks them
+	nsids     []string // NSIDs to generate, each optionally followed by "=Method"
+}
+
+func main() {
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "genrequests: %v\n", err)
+		os.Exit(2)
+	}
+	src, err := generate(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "genrequests: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(opts.out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "genrequests: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses the command line of the generator.
+func parseArgs(args []string) (options, error) {
+	var (
+		opts      options
+		datetimes string
+		enums     string
+		outputs   string
+	)
+	flags := flag.NewFlagSet("genrequests", flag.ContinueOnError)
+	flags.StringVar(&opts.lexicons, "lexicons", "lexicons", "directory holding the lexicon schemas")
+	flags.StringVar(&opts.out, "out", "requests_gen.go", "file to write the generated code to")
+	flags.StringVar(&datetimes, "datetime", "", "comma separated nsid.param string parameters to expose as time.Time")
+	flags.StringVar(&enums, "enum", "", "comma separated nsid.param=a|b string parameters to restrict to the given values")
+	flags.StringVar(&outputs, "output", "", "comma separated NSIDs whose output types to generate too")
+	if err := flags.Parse(args); err != nil {
+		return options{}, err
+	}
+	if datetimes != "" {
+		opts.datetimes = strings.Split(datetimes, ",")
+	}
+	if enums != "" {
+		opts.enums = strings.Split(enums, ",")
+	}
+	if outputs != "" {
+		opts.outputs = strings.Split(outputs, ",")
+	}
+	opts.nsids = flags.Args()
+	return opts, nil
+}
+
+// generate returns the formatted source for the requested endpoints, each given
+// as an NSID optionally followed by "=Method".
+func generate(opts options) ([]byte, error) {
+	if len(opts.nsids) == 0 {
+		return nil, errors.New("no NSIDs given")
+	}
+	isDatetime := make(map[string]bool)
+	for _, d := range opts.datetimes {
+		isDatetime[d] = true
+	}
+	enums := make(map[string][]string)
+	for _, e := range opts.enums {
+		param, values, ok := strings.Cut(e, "=")
+		if !ok || values == "" {
+			return nil, fmt.Errorf("invalid enum %q, want nsid.param=a|b", e)
+		}
+		enums[param] = strings.Split(values, "|")
+	}
+	isOutput := make(map[string]bool)
+	for _, o := range opts.outputs {
+		isOutput[o] = true
+	}
+
+	var data struct {
+		Endpoints []endpoint
+		Packages  []string
+		Time      bool
+	}
+	usedPackages := make(map[string]bool)
+	for _, arg := range opts.nsids {
+		nsid, method, _ := strings.Cut(arg, "=")
+		s, err := readSchema(opts.lexicons, nsid)
+		if err != nil {
+			return nil, err
+		}
+		e, err := newEndpoint(s, method, isDatetime, enums, isOutput[nsid])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", nsid, err)
+		}
+		types := []string{e.Output}
+		for _, p := range e.Params {
+			types = append(types, p.Type)
+		}
+		for _, f := range e.Fields {
+			types = append(types, f.Type)
+		}
+		for _, t := range types {
+			if pkg, _, ok := strings.Cut(strings.TrimLeft(t, "[]*"), "."); ok {
+				usedPackages[pkg] = true
+			}
+		}
+		data.Endpoints = append(data.Endpoints, e)
+	}
+	data.Time = usedPackages["time"]
+	delete(usedPackages, "time")
+	for pkg := range usedPackages {
+		data.Packages = append(data.Packages, pkg)
+	}
+	sort.Strings(data.Packages)
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+// readSchema loads the lexicon of an NSID, e.g. app.bsky.feed.searchPosts from
+// lexicons/app/bsky/feed/searchPosts.json.
+func readSchema(lexicons string, nsid string) (*schema, error) {
+	path := filepath.Join(lexicons, filepath.Join(strings.Split(nsid, ".")...)+".json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.ID != nsid {
+		return nil, fmt.Errorf("%s: holds lexicon %q instead of %q", path, s.ID, nsid)
+	}
+	return &s, nil
+}
+
+// newEndpoint converts the main definition of a query lexicon into the template input.
+func newEndpoint(s *schema, method string, isDatetime map[string]bool, enums map[string][]string, output bool) (endpoint, error) {
+	main, ok := s.Defs["main"]
+	if !ok || main.Type != "query" {
+		return endpoint{}, errors.New("only queries are supported")
+	}
+	if main.Output == nil || main.Output.Encoding != "application/json" {
+		return endpoint{}, errors.New("only queries with JSON output are supported")
+	}
+
+	segments := strings.Split(s.ID, ".")
+	if len(segments) < 4 {
+		return endpoint{}, errors.New("invalid NSID")
+	}
+	pkg, ok := packages[strings.Join(segments[:2], ".")]
+	if !ok {
+		return endpoint{}, fmt.Errorf("unknown namespace %s", strings.Join(segments[:2], "."))
+	}
+	if method == "" {
+		method = upperFirst(segments[len(segments)-1])
+	}
+
+	e := endpoint{
+		NSID:        s.ID,
+		Method:      method,
+		Request:     method + "Request",
+		Output:      pkg + "." + upperFirst(segments[len(segments)-2]) + upperFirst(segments[len(segments)-1]) + "_Output",
+		Description: wrap(method+" calls "+s.ID+". "+main.Description, 77),
+	}
+	if output {
+		e.Output = upperFirst(segments[len(segments)-2]) + upperFirst(segments[len(segments)-1]) + "_Output"
+
+		fields, err := newFields(main.Output.Schema, s.ID)
+		if err != nil {
+			return endpoint{}, fmt.Errorf("output: %w", err)
+		}
+		e.Fields = fields
+	}
+	if main.Parameters == nil {
+		return e, nil
+	}
+
+	names := make([]string, 0, len(main.Parameters.Properties))
+	for name := range main.Parameters.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, err := newParam(name, main.Parameters.Properties[name], contains(main.Parameters.Required, name), isDatetime[s.ID+"."+name], enums[s.ID+"."+name])
+		if err != nil {
+			return endpoint{}, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		e.Params = append(e.Params, p)
+	}
+	return e, nil
+}
+
+// newParam converts a query parameter into a request field. Known values of the
+// lexicon are open-ended and not enforced, unless enum restricts the parameter.
+func newParam(name string, t *typeSchema, required bool, datetime bool, enum []string) (param, error) {
+	p := param{Field: upperFirst(name)}
+
+	tag := []string{name}
+	if required {
+		tag = append(tag, "required")
+	} else {
+		tag = append(tag, "omitempty")
+	}
+
+	constraints := t
+	switch t.Type {
+	case "string":
+		p.Type = "string"
+	case "integer":
+		p.Type = "int"
+	case "boolean":
+		p.Type = "bool"
+	case "array":
+		if t.Items == nil {
+			return param{}, errors.New("array without items")
+		}
+		switch t.Items.Type {
+		case "string":
+			p.Type = "[]string"
+		case "integer":
+			p.Type = "[]int"
+		default:
+			return param{}, fmt.Errorf("unsupported array items %s", t.Items.Type)
+		}
+		// Formats and values constrain the elements, lengths the array itself
+		constraints = &typeSchema{
+			Format:    t.Items.Format,
+			Enum:      t.Items.Enum,
+			MaxLength: t.MaxLength,
+		}
+	default:
+		return param{}, fmt.Errorf("unsupported type %s", t.Type)
+	}
+
+	if datetime || constraints.Format == "datetime" {
+		p.Type = strings.Replace(p.Type, "string", "time.Time", 1)
+		tag = append(tag, "datetime")
+	} else if constraints.Format != "" {
+		tag = append(tag, "format="+constraints.Format)
+	}
+	if enum == nil {
+		enum = constraints.Enum
+	}
+	if len(enum) > 0 {
+		tag = append(tag, "enum="+strings.Join(enum, "|"))
+	}
+	if constraints.Minimum != nil {
+		tag = append(tag, fmt.Sprintf("minimum=%d", *constraints.Minimum))
+	}
+	if constraints.Maximum != nil {
+		tag = append(tag, fmt.Sprintf("maximum=%d", *constraints.Maximum))
+	}
+	if constraints.MaxLength != nil {
+		tag = append(tag, fmt.Sprintf("maxLength=%d", *constraints.MaxLength))
+	}
+	p.Tag = strings.Join(tag, ",")
+
+	description := t.Description
+	if t.Default != nil {
+		description = strings.TrimSpace(fmt.Sprintf("%s Defaults to %v.", description, t.Default))
+	}
+	p.Description = wrap(description, 76)
+	return p, nil
+}
+
+// newFields converts the object schema of a query output into the fields of a
+// struct, typed like indigo would.
+func newFields(t *typeSchema, nsid string) ([]field, error) {
+	if t == nil || t.Type != "object" {
+		return nil, errors.New("only object outputs are supported")
+	}
+	names := make([]string, 0, len(t.Properties))
+	for name := range t.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []field
+	for _, name := range names {
+		required := contains(t.Required, name)
+		typ, err := goType(t.Properties[name], nsid, required)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", name, err)
+		}
+		tag := name
+		if !required {
+			tag += ",omitempty"
+		}
+		fields = append(fields, field{Name: upperFirst(name), Type: typ, Tag: tag})
+	}
+	return fields, nil
+}
+
+// goType returns the Go type of an output property, pointers marking optional
+// scalars and references.
+func goType(t *typeSchema, nsid string, required bool) (string, error) {
+	var typ string
+	switch t.Type {
+	case "string":
+		typ = "string"
+	case "integer":
+		typ = "int64"
+	case "boolean":
+		typ = "bool"
+	case "ref":
+		ref, err := refType(t.Ref, nsid)
+		if err != nil {
+			return "", err
+		}
+		return "*" + ref, nil
+	case "array":
+		if t.Items == nil {
+			return "", errors.New("array without items")
+		}
+		items, err := goType(t.Items, nsid, true)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + items, nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", t.Type)
+	}
+	if !required {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// refType returns the indigo type of a lexicon reference, e.g.
+// bsky.GraphDefs_StarterPackViewBasic for app.bsky.graph.defs#starterPackViewBasic.
+func refType(ref string, nsid string) (string, error) {
+	id, def, _ := strings.Cut(ref, "#")
+	if id == "" {
+		id = nsid
+	}
+	segments := strings.Split(id, ".")
+	if len(segments) < 4 {
+		return "", fmt.Errorf("invalid reference %s", ref)
+	}
+	pkg, ok := packages[strings.Join(segments[:2], ".")]
+	if !ok {
+		return "", fmt.Errorf("unknown namespace of reference %s", ref)
+	}
+	name := pkg + "." + upperFirst(segments[len(segments)-2]) + upperFirst(segments[len(segments)-1])
+	if def != "" && def != "main" {
+		name += "_" + upperFirst(def)
+	}
+	return name, nil
+}
+
+// upperFirst turns a lexicon name into an exported Go identifier.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// wrap splits text into lines of at most width characters, breaking at spaces.
+func wrap(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by genrequests from the lexicons directory. DO NOT EDIT.
+
+package bluesky
+
+import (
+	"context"
+{{- if .Time}}
+	"time"
+{{- end}}
+{{range .Packages}}
+	"github.com/bluesky-social/indigo/api/{{.}}"
+{{- end}}
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+{{range .Endpoints}}
+{{- if .Fields}}
+// {{.Output}} is the output of {{.NSID}}.
+type {{.Output}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `json:"{{.Tag}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+// {{.Request}} holds the parameters of {{.NSID}}.
+// Fields are validated against the lexicon before the request is sent.
+type {{.Request}} struct {
+{{- range $i, $p := .Params}}
+{{- if $i}}
+{{end}}
+{{range .Description}}	// {{.}}
+{{end}}	{{.Field}} {{.Type}} ` + "`" + `xrpc:"{{.Tag}}"` + "`" + `
+{{- end}}
+}
+{{range .Description}}
+// {{.}}
+{{- end}}
+func (c *client) {{.Method}}(ctx context.Context, request *{{.Request}}) (*{{.Output}}, error) {
+	if err := validateRequest("{{.NSID}}", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out {{.Output}}
+	if err := c.do(ctx, xrpc.Query, "", "{{.NSID}}", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call {{.NSID}}.")
+		return nil, err
+	}
+	return &out, nil
+}
+{{end}}`))
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that the checked in requests match what the generator produces from the
+// vendored lexicons, i.e. that `go generate` was run after changing either.
+func TestGeneratedRequestsUpToDate(t *testing.T) {
+	client, err := os.ReadFile("../../../client.go")
+	if err != nil {
+		t.Fatalf("failed to read client: %v", err)
+	}
+	var directive string
+	for _, line := range strings.Split(string(client), "\n") {
+		if strings.HasPrefix(line, "//go:generate go run ./internal/cmd/genrequests ") {
+			directive = strings.TrimPrefix(line, "//go:generate go run ./internal/cmd/genrequests ")
+		}
+	}
+	opts, err := parseArgs(strings.Fields(directive))
+	if err != nil {
+		t.Fatalf("failed to parse go:generate directive %q: %v", directive, err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("../../..", opts.out))
+	if err != nil {
+		t.Fatalf("failed to read generated requests: %v", err)
+	}
+	opts.lexicons = filepath.Join("../../..", opts.lexicons)
+	got, err := generate(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "generated requests are stale, run go generate")
+}
+
+// Tests the struct tags generated from the lexicon constraints.
+func TestNewParam(t *testing.T) {
+	one, hundred, ten := int64(1), int64(100), 10
+
+	tests := []struct {
+		name     string
+		schema   *typeSchema
+		required bool
+		enum     []string
+		field    string
+		typ      string
+		tag      string
+	}{
+		{"q", &typeSchema{Type: "string"}, true, nil, "Q", "string", "q,required"},
+		{"limit", &typeSchema{Type: "integer", Minimum: &one, Maximum: &hundred}, false, nil, "Limit", "int", "limit,omitempty,minimum=1,maximum=100"},
+		{"actor", &typeSchema{Type: "string", Format: "at-identifier"}, true, nil, "Actor", "string", "actor,required,format=at-identifier"},
+		{"filter", &typeSchema{Type: "string", KnownValues: []string{"a", "b"}}, false, nil, "Filter", "string", "filter,omitempty"},
+		{"sort", &typeSchema{Type: "string", KnownValues: []string{"a", "b"}}, false, []string{"a", "b"}, "Sort", "string", "sort,omitempty,enum=a|b"},
+		{"order", &typeSchema{Type: "string", Enum: []string{"asc", "desc"}}, false, nil, "Order", "string", "order,omitempty,enum=asc|desc"},
+		{"includePins", &typeSchema{Type: "boolean"}, false, nil, "IncludePins", "bool", "includePins,omitempty"},
+		{"uris", &typeSchema{Type: "array", MaxLength: &ten, Items: &typeSchema{Type: "string", Format: "at-uri"}}, true, nil, "Uris", "[]string", "uris,required,format=at-uri,maxLength=10"},
+		{"since", &typeSchema{Type: "string", Format: "datetime"}, false, nil, "Since", "time.Time", "since,omitempty,datetime"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := newParam(test.name, test.schema, test.required, false, test.enum)
+			assert.NoError(t, err)
+			assert.Equal(t, test.field, p.Field)
+			assert.Equal(t, test.typ, p.Type)
+			assert.Equal(t, test.tag, p.Tag)
+		})
+	}
+
+	_, err := newParam("blob", &typeSchema{Type: "blob"}, false, false, nil)
+	assert.Error(t, err)
+}
+
+// Tests the output types generated for lexicons missing from indigo.
+func TestNewFields(t *testing.T) {
+	fields, err := newFields(&typeSchema{
+		Type:     "object",
+		Required: []string{"packs"},
+		Properties: map[string]*typeSchema{
+			"cursor": {Type: "string"},
+			"total":  {Type: "integer"},
+			"packs":  {Type: "array", Items: &typeSchema{Type: "ref", Ref: "app.bsky.graph.defs#starterPackViewBasic"}},
+			"self":   {Type: "ref", Ref: "#view"},
+		},
+	}, "app.bsky.graph.searchStarterPacks")
+	assert.NoError(t, err)
+	assert.Equal(t, []field{
+		{Name: "Cursor", Type: "*string", Tag: "cursor,omitempty"},
+		{Name: "Packs", Type: "[]*bsky.GraphDefs_StarterPackViewBasic", Tag: "packs"},
+		{Name: "Self", Type: "*bsky.GraphSearchStarterPacks_View", Tag: "self,omitempty"},
+		{Name: "Total", Type: "*int64", Tag: "total,omitempty"},
+	}, fields)
+}
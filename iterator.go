@@ -0,0 +1,132 @@
+package bluesky
+
+import (
+	"context"
+)
+
+// Iterator walks through the results of a cursor paginated XRPC query, fetching
+// new pages from the server on demand. Items that were already returned on an
+// earlier page are skipped.
+//
+//	it := client.SearchPostsIter(ctx, &SearchPostsRequest{Q: "peterman"}, 100)
+//	for it.Next() {
+//		post := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, cursor string) ([]T, string, error) // Retrieves the page at cursor and the cursor of the next one
+	key   func(T) string                                                // Identity of an item, used to drop duplicates across pages
+	max   int                                                           // Maximum number of items to return, 0 meaning unlimited
+
+	page    []T                 // Items of the current page not yet returned
+	item    T                   // Item returned by the last call to Next
+	cursor  string              // Cursor of the next page to fetch
+	seen    map[string]struct{} // Keys of all items returned so far
+	yielded int                 // Number of items returned so far
+	done    bool                // Whether the server has no more pages
+	err     error               // Failure that terminated the iteration
+}
+
+func newIterator[T any](ctx context.Context, cursor string, max int, fetch func(context.Context, string) ([]T, string, error), key func(T) string) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:    ctx,
+		fetch:  fetch,
+		key:    key,
+		max:    max,
+		cursor: cursor,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// newQueryIterator iterates over the results of a cursor paginated query. It
+// pages with a copy of the request, so that paging doesn't mess with the
+// caller's request, starting at the request's cursor. The cursor function
+// points into a request, page extracts the items and next cursor of an output.
+func newQueryIterator[Req any, Out any, T any](ctx context.Context, request *Req, max int, query func(context.Context, *Req) (*Out, error), cursor func(*Req) *string, page func(*Out) ([]T, *string), key func(T) string) *Iterator[T] {
+	var req Req
+	if request != nil {
+		req = *request
+	}
+	fetch := func(ctx context.Context, at string) ([]T, string, error) {
+		*cursor(&req) = at
+
+		out, err := query(ctx, &req)
+		if err != nil {
+			return nil, "", err
+		}
+		items, next := page(out)
+		if next == nil {
+			return items, "", nil
+		}
+		return items, *next, nil
+	}
+	return newIterator(ctx, *cursor(&req), max, fetch, key)
+}
+
+// Next advances the iterator to the next item, fetching a new page if needed.
+// It returns false once the results are exhausted, the maximum number of items
+// was reached, the context is done or a request failed. Err tells these apart.
+func (it *Iterator[T]) Next() bool {
+	for {
+		if it.err != nil || (it.max > 0 && it.yielded >= it.max) {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		// Serve from the current page if anything is left on it
+		for len(it.page) > 0 {
+			item := it.page[0]
+			it.page = it.page[1:]
+
+			if it.key != nil {
+				key := it.key(item)
+				if _, ok := it.seen[key]; ok {
+					continue
+				}
+				it.seen[key] = struct{}{}
+			}
+			it.item = item
+			it.yielded++
+			return true
+		}
+		if it.done {
+			return false
+		}
+		// Page exhausted, retrieve the next one
+		items, cursor, err := it.fetch(it.ctx, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		// An empty page or a cursor that doesn't move means there's nothing more
+		if cursor == "" || cursor == it.cursor || len(items) == 0 {
+			it.done = true
+		}
+		it.page, it.cursor = items, cursor
+	}
+}
+
+// Item returns the item the iterator currently points to.
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error that stopped the iteration, if any. Running out of
+// results or hitting the maximum item count is not an error.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Cursor returns the cursor of the next page to be fetched, which can be used
+// to resume the iteration later on. It is empty if there are no more pages.
+func (it *Iterator[T]) Cursor() string {
+	if it.done {
+		return ""
+	}
+	return it.cursor
+}
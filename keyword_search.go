@@ -4,26 +4,13 @@ import (
 	"context"
 
 	"github.com/bluesky-social/indigo/api/bsky"
-	"github.com/bluesky-social/indigo/xrpc"
-	"github.com/rs/zerolog/log"
 )
 
-func (c *client) SearchPosts(request *SearchPostsRequest) (*bsky.FeedSearchPosts_Output, error) {
-	params, err := getParamMap(request)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if _, exists := params["limit"]; !exists {
-		log.Warn().Msg("Did not receive a limit for SearchPosts, falling back to a default of 25.")
-		params["limit"] = 25
-	}
-
-	var out bsky.FeedSearchPosts_Output
-	if err := c.client.Do(context.Background(), xrpc.Query, "", "app.bsky.feed.searchPosts", params, nil, &out); err != nil {
-		log.Err(err).Msg("Failed to search.")
-		return nil, err
-	}
-	return &out, nil
+func (c *client) SearchPostsIter(ctx context.Context, request *SearchPostsRequest, maxResults int) *Iterator[*bsky.FeedDefs_PostView] {
+	return newQueryIterator(ctx, request, maxResults, c.SearchPosts,
+		func(req *SearchPostsRequest) *string { return &req.Cursor },
+		func(out *bsky.FeedSearchPosts_Output) ([]*bsky.FeedDefs_PostView, *string) {
+			return out.Posts, out.Cursor
+		},
+		func(post *bsky.FeedDefs_PostView) string { return post.Uri })
 }
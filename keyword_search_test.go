@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bluesky-social/indigo/xrpc"
 	"github.com/stretchr/testify/assert"
@@ -116,7 +117,7 @@ func TestKeywordSearch(t *testing.T) {
 		Limit: 1,
 	}
 
-	posts, err := c.SearchPosts(&request)
+	posts, err := c.SearchPosts(context.Background(), &request)
 
 	if err != nil {
 		t.Fatal("Failed to search posts")
@@ -130,3 +131,168 @@ func TestKeywordSearch(t *testing.T) {
 	}
 	assert.Equal(t, cursor, 1)
 }
+
+// Tests that a search with an already cancelled context fails with
+// context.Canceled without ever reaching the server.
+func TestKeywordSearchCancelled(t *testing.T) {
+	mockTransport := newDefaultMockRoundTripper()
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey", withXrpcClient(&xrpc.Client{
+		Client: &http.Client{
+			Transport: mockTransport,
+		},
+		Host: ServerBskySocial,
+	}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = c.SearchPosts(ctx, &SearchPostsRequest{Q: "peterman"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
+
+// Tests that a search outliving its deadline fails with context.DeadlineExceeded
+// instead of a transport error.
+func TestKeywordSearchDeadline(t *testing.T) {
+	mockTransport := &hangingRoundTripper{
+		mockRoundTripper: newDefaultMockRoundTripper(),
+		path:             "/xrpc/app.bsky.feed.searchPosts",
+	}
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey", withXrpcClient(&xrpc.Client{
+		Client: &http.Client{
+			Transport: mockTransport,
+		},
+		Host: ServerBskySocial,
+	}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.SearchPosts(ctx, &SearchPostsRequest{Q: "peterman"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
+
+// Returns a post view JSON with the given URI. The CID is reused across posts
+// as nothing in the client depends on it.
+func getPostStr(uri string) string {
+	return fmt.Sprintf(`{
+		"uri": "%s",
+		"cid": "bafyreic27io7r2mt3fng5nco7xrulxpfe63sto5urr7e6sfjrtwsm7tjke",
+		"author": {"did": "did:plc:test", "handle": "test.bsky.social"},
+		"record": {"$type": "app.bsky.feed.post", "createdAt": "2025-02-08T18:07:05Z", "text": "peterman"},
+		"indexedAt": "2025-02-08T18:07:05.920Z"
+	}`, uri)
+}
+
+// Returns a mock transport serving search result pages keyed by the request
+// cursor, the first page being served for an empty cursor.
+func newSearchPagesRoundTripper(pages map[string]string) *mockRoundTripper {
+	return newPagesRoundTripper("app.bsky.feed.searchPosts", pages)
+}
+
+// Returns a mock transport serving result pages of a paginated XRPC query keyed
+// by the request cursor, the first page being served for an empty cursor.
+func newPagesRoundTripper(method string, pages map[string]string) *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/"+method] = func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(pages[req.URL.Query().Get("cursor")])),
+		}
+	}
+	return mockTransport
+}
+
+func newMockClient(t *testing.T, transport http.RoundTripper) Client {
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey", withXrpcClient(&xrpc.Client{
+		Client: &http.Client{
+			Transport: transport,
+		},
+		Host: ServerBskySocial,
+	}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	return c
+}
+
+// Tests that the search iterator follows cursors until they run out, skipping
+// posts that were already seen on a previous page.
+func TestKeywordSearchIter(t *testing.T) {
+	mockTransport := newSearchPagesRoundTripper(map[string]string{
+		"":  fmt.Sprintf(`{"posts": [%s, %s], "cursor": "2"}`, getPostStr("at://a"), getPostStr("at://b")),
+		"2": fmt.Sprintf(`{"posts": [%s, %s], "cursor": "3"}`, getPostStr("at://b"), getPostStr("at://c")),
+		"3": fmt.Sprintf(`{"posts": [%s]}`, getPostStr("at://d")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	request := &SearchPostsRequest{Q: "peterman", Limit: 2}
+	it := c.SearchPostsIter(context.Background(), request, 0)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://c", "at://d"}, uris)
+	assert.Equal(t, 3, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+	assert.Equal(t, "", it.Cursor())
+
+	// The caller's request must not be modified by the paging
+	assert.Equal(t, "", request.Cursor)
+}
+
+// Tests that the search iterator stops fetching pages once the maximum number
+// of results was returned.
+func TestKeywordSearchIterMaxResults(t *testing.T) {
+	mockTransport := newSearchPagesRoundTripper(map[string]string{
+		"":  fmt.Sprintf(`{"posts": [%s, %s], "cursor": "2"}`, getPostStr("at://a"), getPostStr("at://b")),
+		"2": fmt.Sprintf(`{"posts": [%s, %s], "cursor": "3"}`, getPostStr("at://c"), getPostStr("at://d")),
+		"3": fmt.Sprintf(`{"posts": [%s]}`, getPostStr("at://e")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	it := c.SearchPostsIter(context.Background(), &SearchPostsRequest{Q: "peterman", Limit: 2}, 3)
+
+	var uris []string
+	for it.Next() {
+		uris = append(uris, it.Item().Uri)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"at://a", "at://b", "at://c"}, uris)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+	assert.Equal(t, "3", it.Cursor())
+}
+
+// Tests that the search iterator stops with the context error once the context
+// is cancelled mid-iteration.
+func TestKeywordSearchIterCancelled(t *testing.T) {
+	mockTransport := newSearchPagesRoundTripper(map[string]string{
+		"":  fmt.Sprintf(`{"posts": [%s], "cursor": "2"}`, getPostStr("at://a")),
+		"2": fmt.Sprintf(`{"posts": [%s], "cursor": "3"}`, getPostStr("at://b")),
+	})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := c.SearchPostsIter(ctx, &SearchPostsRequest{Q: "peterman"}, 0)
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "at://a", it.Item().Uri)
+
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
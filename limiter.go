@@ -0,0 +1,343 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// RateLimiter throttles the XRPC calls of a client before they leave, so that
+// the client stays within the server's rate limits instead of being rejected.
+type RateLimiter interface {
+	// Wait blocks until a call to the given XRPC method costing the given number
+	// of points is allowed to proceed. It returns an error if the call should
+	// not be made, e.g. because the context was cancelled or the limiter does
+	// not want to wait.
+	Wait(ctx context.Context, method string, points int) error
+
+	// Update resynchronises the limiter with the rate limit state reported by
+	// the server in the response to a call to the given XRPC method.
+	Update(method string, info *xrpc.RatelimitInfo)
+}
+
+// WithRateLimiter makes the client consult the given limiter before every XRPC
+// call, and feeds it the rate limit headers of every response.
+func WithRateLimiter(limiter RateLimiter) clientOption {
+	return func(params *clientOptionalParams) {
+		params.limiter = limiter
+	}
+}
+
+// RateLimitBudget is the number of points that may be spent within a window.
+type RateLimitBudget struct {
+	Points int
+	Window time.Duration
+}
+
+// RateLimits are the budgets a TokenBucketLimiter enforces per group of XRPC
+// methods. A call must fit into every budget of its group to proceed.
+type RateLimits struct {
+	CreateSession  []RateLimitBudget // com.atproto.server.createSession
+	RefreshSession []RateLimitBudget // com.atproto.server.refreshSession
+	Writes         []RateLimitBudget // Repo writes, charged per record (create 3, update 2, delete 1)
+	Reads          []RateLimitBudget // Every other call
+}
+
+// BlueskyRateLimits are the limits published for accounts hosted on the Bluesky
+// PDSs. https://docs.bsky.app/docs/advanced-guides/rate-limits
+var BlueskyRateLimits = RateLimits{
+	CreateSession: []RateLimitBudget{{Points: 30, Window: 5 * time.Minute}, {Points: 300, Window: 24 * time.Hour}},
+	// Not published separately, falls under the overall API limit
+	RefreshSession: []RateLimitBudget{{Points: 3000, Window: 5 * time.Minute}},
+	Writes:         []RateLimitBudget{{Points: 5000, Window: time.Hour}, {Points: 35000, Window: 24 * time.Hour}},
+	Reads:          []RateLimitBudget{{Points: 3000, Window: 5 * time.Minute}},
+}
+
+// ErrExceedsRateLimitBudget is returned by a TokenBucketLimiter for calls costing
+// more points than one of their budgets holds, which could never proceed. Large
+// write batches must be split up, or the budget raised.
+var ErrExceedsRateLimitBudget = errors.New("call exceeds rate limit budget")
+
+// errLimiterExhausted is wrapped into the RateLimitError returned by a fail fast
+// TokenBucketLimiter, to tell it apart from limits enforced by the server.
+var errLimiterExhausted = errors.New("client rate limit budget exhausted")
+
+// TokenBucketLimiter is a RateLimiter keeping a token bucket for every budget,
+// refilled continuously over the budget's window. Once the server reports the
+// state of a limit, the matching bucket follows the server's window instead.
+type TokenBucketLimiter struct {
+	clock    clockInterface
+	failFast bool // Whether to return an error instead of waiting for tokens
+
+	lock    sync.Mutex
+	buckets map[string][]*tokenBucket // Buckets for each method group
+}
+
+type tokenBucket struct {
+	capacity float64       // Maximum number of tokens in the bucket
+	window   time.Duration // Time it takes for an empty bucket to fill up
+	tokens   float64       // Number of tokens currently available
+	refilled time.Time     // Last time tokens were added to the bucket
+	resetAt  time.Time     // Server reported window end, zero if not synced
+}
+
+// NewTokenBucketLimiter creates a limiter enforcing the given limits. If failFast
+// is set, calls exceeding a budget are rejected right away with a RateLimitError
+// instead of waiting for the budget to replenish.
+func NewTokenBucketLimiter(limits RateLimits, failFast bool) *TokenBucketLimiter {
+	return newTokenBucketLimiter(limits, failFast, &realClockImpl{})
+}
+
+func newTokenBucketLimiter(limits RateLimits, failFast bool, clock clockInterface) *TokenBucketLimiter {
+	l := &TokenBucketLimiter{
+		clock:    clock,
+		failFast: failFast,
+		buckets:  make(map[string][]*tokenBucket),
+	}
+	groups := map[string][]RateLimitBudget{
+		"createSession":  limits.CreateSession,
+		"refreshSession": limits.RefreshSession,
+		"writes":         limits.Writes,
+		"reads":          limits.Reads,
+	}
+	for group, budgets := range groups {
+		for _, budget := range budgets {
+			l.buckets[group] = append(l.buckets[group], &tokenBucket{
+				capacity: float64(budget.Points),
+				window:   budget.Window,
+				tokens:   float64(budget.Points),
+				refilled: clock.Now(),
+			})
+		}
+	}
+	return l
+}
+
+// rateLimitGroup returns the group of budgets an XRPC method is charged to.
+func rateLimitGroup(method string) string {
+	switch method {
+	case "com.atproto.server.createSession":
+		return "createSession"
+	case "com.atproto.server.refreshSession":
+		return "refreshSession"
+	case "com.atproto.repo.createRecord", "com.atproto.repo.putRecord",
+		"com.atproto.repo.deleteRecord", "com.atproto.repo.applyWrites":
+		return "writes"
+	default:
+		return "reads"
+	}
+}
+
+// rateLimitPoints returns the number of points a call costs within its group.
+// Writes are charged per record, everything else per call.
+func rateLimitPoints(method string, bodyobj interface{}) int {
+	switch method {
+	case "com.atproto.repo.createRecord":
+		return 3
+	case "com.atproto.repo.putRecord":
+		return 2
+	case "com.atproto.repo.applyWrites":
+		input, ok := bodyobj.(*atproto.RepoApplyWrites_Input)
+		if !ok {
+			return 1
+		}
+		points := 0
+		for _, write := range input.Writes {
+			switch {
+			case write.RepoApplyWrites_Create != nil:
+				points += 3
+			case write.RepoApplyWrites_Update != nil:
+				points += 2
+			case write.RepoApplyWrites_Delete != nil:
+				points += 1
+			}
+		}
+		return points
+	default:
+		return 1
+	}
+}
+
+func (l *TokenBucketLimiter) Wait(ctx context.Context, method string, points int) error {
+	for {
+		wait, err := l.take(method, points)
+		if err != nil || wait == 0 {
+			return err
+		}
+		timer := l.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take withdraws the given number of points from every bucket of the method's
+// group if all of them can afford it. Otherwise it returns how long to wait
+// before trying again, or an error if the limiter fails fast. Calls no bucket
+// could ever afford are rejected regardless, as waiting would not help.
+func (l *TokenBucketLimiter) take(method string, points int) (time.Duration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var (
+		now     = l.clock.Now()
+		buckets = l.buckets[rateLimitGroup(method)]
+		wait    time.Duration
+		short   *tokenBucket
+	)
+	for _, bucket := range buckets {
+		if float64(points) > bucket.capacity {
+			return 0, fmt.Errorf("%w: %s costs %d points, budget holds %d per %v", ErrExceedsRateLimitBudget, method, points, int(bucket.capacity), bucket.window)
+		}
+		bucket.refill(now)
+		if bucketWait := bucket.waitFor(now, float64(points)); bucketWait > wait {
+			wait, short = bucketWait, bucket
+		}
+	}
+	if short == nil {
+		for _, bucket := range buckets {
+			bucket.tokens -= float64(points)
+		}
+		return 0, nil
+	}
+	if l.failFast {
+		return 0, &RateLimitError{
+			Limit:     int(short.capacity),
+			Remaining: int(short.tokens),
+			Reset:     now.Add(wait),
+			Err:       errLimiterExhausted,
+		}
+	}
+	return wait, nil
+}
+
+func (l *TokenBucketLimiter) Update(method string, info *xrpc.RatelimitInfo) {
+	window, ok := parseRateLimitWindow(info.Policy)
+	if !ok || info.Limit <= 0 {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	group := rateLimitGroup(method)
+	for _, bucket := range l.buckets[group] {
+		if bucket.window == window {
+			bucket.sync(l.clock.Now(), info)
+			return
+		}
+	}
+	// The server enforces a limit we didn't know about, start tracking it
+	bucket := &tokenBucket{window: window}
+	bucket.sync(l.clock.Now(), info)
+	l.buckets[group] = append(l.buckets[group], bucket)
+}
+
+// refill adds the tokens accumulated since the last refill. Buckets synced with
+// the server are refilled all at once when the server's window resets.
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.resetAt.IsZero() {
+		if now.Before(b.resetAt) {
+			return
+		}
+		b.tokens, b.refilled, b.resetAt = b.capacity, now, time.Time{}
+		return
+	}
+	if elapsed := now.Sub(b.refilled); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+b.capacity*elapsed.Seconds()/b.window.Seconds())
+		b.refilled = now
+	}
+}
+
+// waitFor returns how long it takes until the bucket holds the given number of
+// tokens, 0 if it already does.
+func (b *tokenBucket) waitFor(now time.Time, tokens float64) time.Duration {
+	if b.tokens >= tokens {
+		return 0
+	}
+	if !b.resetAt.IsZero() {
+		return b.resetAt.Sub(now)
+	}
+	missing := tokens - b.tokens
+	return time.Duration(missing / b.capacity * float64(b.window))
+}
+
+// sync overwrites the bucket state with the one reported by the server.
+func (b *tokenBucket) sync(now time.Time, info *xrpc.RatelimitInfo) {
+	b.capacity = float64(info.Limit)
+	b.tokens = float64(info.Remaining)
+	b.refilled = now
+	b.resetAt = info.Reset
+}
+
+// parseRateLimitWindow extracts the window from a RateLimit-Policy header value
+// of the form "3000;w=300".
+func parseRateLimitWindow(policy string) (time.Duration, bool) {
+	for _, param := range strings.Split(policy, ";")[1:] {
+		if seconds, found := strings.CutPrefix(strings.TrimSpace(param), "w="); found {
+			n, err := strconv.Atoi(seconds)
+			if err != nil {
+				return 0, false
+			}
+			return time.Duration(n) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// parseRateLimitHeaders extracts the rate limit state from the RateLimit-*
+// headers of a response, or nil if the server didn't send them.
+func parseRateLimitHeaders(header http.Header) *xrpc.RatelimitInfo {
+	limit, err := strconv.Atoi(header.Get("RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	info := &xrpc.RatelimitInfo{
+		Limit:  limit,
+		Policy: header.Get("RateLimit-Policy"),
+	}
+	if n, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil {
+		info.Remaining = n
+	}
+	if n, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		info.Reset = time.Unix(n, 0)
+	}
+	return info
+}
+
+// rateLimitTransport is an http.RoundTripper reporting the rate limit headers
+// of every XRPC response to a limiter.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter RateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if info := parseRateLimitHeaders(resp.Header); info != nil {
+		t.limiter.Update(strings.TrimPrefix(req.URL.Path, "/xrpc/"), info)
+	}
+	return resp, nil
+}
+
+// waitLimiter blocks until the limiter permits the call, if there is a limiter.
+func waitLimiter(ctx context.Context, limiter RateLimiter, method string, points int) error {
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx, method, points)
+}
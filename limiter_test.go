@@ -0,0 +1,157 @@
+package bluesky
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that a fail fast limiter rejects calls over budget and lets them
+// through again once enough tokens were refilled.
+func TestLimiterFailFast(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	limiter := newTokenBucketLimiter(RateLimits{
+		Reads: []RateLimitBudget{{Points: 2, Window: time.Minute}},
+	}, true, clock)
+
+	ctx := context.Background()
+	assert.NoError(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1))
+	assert.NoError(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1))
+
+	err := limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// Half the window refills half the budget
+	clock.advance(30 * time.Second)
+	assert.NoError(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1))
+	assert.ErrorIs(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1), ErrRateLimited)
+}
+
+// Tests that calls costing more than a budget holds are rejected right away,
+// whether the limiter fails fast or not.
+func TestLimiterExceedsBudget(t *testing.T) {
+	for _, failFast := range []bool{false, true} {
+		clock := &mockClock{time: time.Now()}
+		limiter := newTokenBucketLimiter(RateLimits{
+			Writes: []RateLimitBudget{{Points: 100, Window: time.Hour}, {Points: 1000, Window: 24 * time.Hour}},
+		}, failFast, clock)
+
+		// A batch of 50 creates costs 150 points
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := limiter.Wait(ctx, "com.atproto.repo.applyWrites", 150)
+		cancel()
+		assert.ErrorIs(t, err, ErrExceedsRateLimitBudget)
+		assert.NotErrorIs(t, err, context.DeadlineExceeded)
+
+		// Nothing was charged for it
+		assert.NoError(t, limiter.Wait(context.Background(), "com.atproto.repo.applyWrites", 100))
+	}
+}
+
+// Tests that writes are charged per record and only against the write budget.
+func TestLimiterWritePoints(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	limiter := newTokenBucketLimiter(RateLimits{
+		Writes: []RateLimitBudget{{Points: 5, Window: time.Hour}},
+		Reads:  []RateLimitBudget{{Points: 1, Window: time.Hour}},
+	}, true, clock)
+
+	ctx := context.Background()
+	assert.NoError(t, limiter.Wait(ctx, "com.atproto.repo.createRecord", rateLimitPoints("com.atproto.repo.createRecord", nil)))
+	assert.ErrorIs(t, limiter.Wait(ctx, "com.atproto.repo.createRecord", rateLimitPoints("com.atproto.repo.createRecord", nil)), ErrRateLimited)
+	assert.NoError(t, limiter.Wait(ctx, "com.atproto.repo.deleteRecord", rateLimitPoints("com.atproto.repo.deleteRecord", nil)))
+	assert.NoError(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1))
+
+	writes := &atproto.RepoApplyWrites_Input{
+		Writes: []*atproto.RepoApplyWrites_Input_Writes_Elem{
+			{RepoApplyWrites_Create: &atproto.RepoApplyWrites_Create{}},
+			{RepoApplyWrites_Update: &atproto.RepoApplyWrites_Update{}},
+			{RepoApplyWrites_Delete: &atproto.RepoApplyWrites_Delete{}},
+		},
+	}
+	assert.Equal(t, 6, rateLimitPoints("com.atproto.repo.applyWrites", writes))
+}
+
+// Tests that the limiter follows the state reported by the server.
+func TestLimiterUpdate(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	limiter := newTokenBucketLimiter(BlueskyRateLimits, true, clock)
+
+	limiter.Update("app.bsky.feed.searchPosts", &xrpc.RatelimitInfo{
+		Limit:     3000,
+		Remaining: 0,
+		Reset:     clock.Now().Add(time.Minute),
+		Policy:    "3000;w=300",
+	})
+	ctx := context.Background()
+	assert.ErrorIs(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1), ErrRateLimited)
+
+	// Only the budget reported by the server is affected
+	assert.NoError(t, limiter.Wait(ctx, "com.atproto.repo.createRecord", 3))
+
+	// Once the server's window resets, the whole budget is available again
+	clock.advance(time.Minute)
+	assert.NoError(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1))
+}
+
+// Tests that a blocking limiter gives up waiting once the context is done.
+func TestLimiterWaitCancelled(t *testing.T) {
+	limiter := NewTokenBucketLimiter(RateLimits{
+		Reads: []RateLimitBudget{{Points: 1, Window: time.Hour}},
+	}, false)
+
+	assert.NoError(t, limiter.Wait(context.Background(), "app.bsky.feed.searchPosts", 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "app.bsky.feed.searchPosts", 1), context.DeadlineExceeded)
+}
+
+// Tests that the client consults the limiter before calls and feeds it the rate
+// limit headers of the responses.
+func TestClientWithLimiter(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	limiter := newTokenBucketLimiter(BlueskyRateLimits, true, clock)
+
+	header := make(http.Header)
+	header.Set("RateLimit-Limit", "3000")
+	header.Set("RateLimit-Remaining", "0")
+	header.Set("RateLimit-Reset", "4102444800")
+	header.Set("RateLimit-Policy", "3000;w=300")
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/app.bsky.feed.searchPosts"] = &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"posts": []}`)),
+	}
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey",
+		withClock(clock),
+		WithRateLimiter(limiter),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.NoError(t, err)
+
+	// The server said the budget is used up, the next call must not leave
+	_, err = c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
@@ -0,0 +1,176 @@
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+)
+
+// linkCardMaxPageSize is the most of a page read when looking for its metadata,
+// in bytes. The metadata lives in the head, so the rest is never needed.
+const linkCardMaxPageSize = 1_000_000
+
+// linkCardMeta is the metadata of a page shown in its link card.
+type linkCardMeta struct {
+	title       string
+	description string
+	image       string // URL of the thumbnail, possibly relative to the page
+}
+
+func (c *client) LinkCard(ctx context.Context, hc *http.Client, uri string) (*bsky.FeedPost_Embed, error) {
+	if err := checkFormat("uri", uri); err != nil {
+		return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: fmt.Sprintf("must be a valid uri: %v", err)}}}
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := fetchLink(ctx, hc, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("fetching link card of %s: unsupported content type %s", uri, mediaType)
+	}
+	meta := parseLinkCardMeta(io.LimitReader(res.Body, linkCardMaxPageSize))
+
+	// The card is still useful without a thumbnail, so failing to fetch one is
+	// only logged. Failing to upload it is a problem with the client though.
+	var thumb *Image
+	if meta.image != "" {
+		thumb, err = fetchThumbnail(ctx, hc, res.Request.URL, meta.image)
+		if err != nil {
+			log.Warn().Err(err).Str("uri", uri).Msg("Failed to fetch link card thumbnail.")
+		}
+	}
+	return c.ExternalEmbed(ctx, uri, meta.title, meta.description, thumb)
+}
+
+// fetchLink GETs a URL, failing on non 2xx responses.
+func fetchLink(ctx context.Context, hc *http.Client, uri string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", uri, res.Status)
+	}
+	return res, nil
+}
+
+// fetchThumbnail downloads the image at ref, resolved against the page URL.
+// Images over the size limit are not downloaded in full.
+func fetchThumbnail(ctx context.Context, hc *http.Client, page *url.URL, ref string) (*Image, error) {
+	imageURL, err := page.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported thumbnail scheme %q", imageURL.Scheme)
+	}
+	res, err := fetchLink(ctx, hc, imageURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := readBlob(res.Body, imageMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	// Servers often get the content type of images wrong, so sniff it instead
+	if _, reason := checkBlob(data, "", "image/", imageMaxSize); reason != "" {
+		return nil, fmt.Errorf("thumbnail %s %s", imageURL, reason)
+	}
+	return &Image{Data: bytes.NewReader(data)}, nil
+}
+
+// parseLinkCardMeta extracts the OpenGraph title, description and image of an
+// HTML page, falling back to its <title> and description meta tag. Parsing
+// stops at the end of the head.
+func parseLinkCardMeta(r io.Reader) linkCardMeta {
+	var (
+		meta     linkCardMeta
+		title    string // Contents of the <title> element
+		desc     string // Content of the description meta tag
+		inTitle  bool
+		tokenize = html.NewTokenizer(r)
+	)
+	for {
+		switch tokenize.Next() {
+		case html.ErrorToken:
+			return meta.withFallbacks(title, desc)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenize.Token()
+			switch token.Data {
+			case "body":
+				return meta.withFallbacks(title, desc)
+			case "title":
+				inTitle = true
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(attr.Val)
+						}
+					case "content":
+						content = strings.TrimSpace(attr.Val)
+					}
+				}
+				switch {
+				case key == "og:title" && meta.title == "":
+					meta.title = content
+				case key == "og:description" && meta.description == "":
+					meta.description = content
+				case (key == "og:image" || key == "og:image:url" || key == "og:image:secure_url") && meta.image == "":
+					meta.image = content
+				case key == "description" && desc == "":
+					desc = content
+				}
+			}
+
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.Join(strings.Fields(string(tokenize.Text())), " ")
+			}
+
+		case html.EndTagToken:
+			switch name, _ := tokenize.TagName(); string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return meta.withFallbacks(title, desc)
+			}
+		}
+	}
+}
+
+// withFallbacks fills the missing title and description from the plain HTML
+// ones.
+func (meta linkCardMeta) withFallbacks(title string, desc string) linkCardMeta {
+	if meta.title == "" {
+		meta.title = title
+	}
+	if meta.description == "" {
+		meta.description = desc
+	}
+	return meta
+}
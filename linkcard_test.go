@@ -0,0 +1,107 @@
+package bluesky
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a web server with pages of various metadata and their thumbnails.
+func newLinkCardServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html><head>
+	<title>Plain title</title>
+	<meta name="description" content="Plain description">
+	<meta property="og:title" content=" OpenGraph &amp; title ">
+	<meta property="og:description" content="OpenGraph description">
+	<meta property="og:image" content="/thumb.png">
+</head><body>
+	<meta property="og:title" content="Not in the head">
+</body></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>
+			Plain   title
+		</title><meta name="description" content="Plain description"></head></html>`))
+	})
+	mux.HandleFunc("/broken-thumb", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta property="og:title" content="Title"><meta property="og:image" content="/missing.png"></head></html>`))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/thumb.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(getPNG(60, 40))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Tests building link cards from OpenGraph metadata, uploading the thumbnail.
+func TestLinkCard(t *testing.T) {
+	server := newLinkCardServer(t)
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	embed, err := c.LinkCard(context.Background(), server.Client(), server.URL+"/article")
+	assert.NoError(t, err)
+	external := embed.EmbedExternal.External
+	assert.Equal(t, server.URL+"/article", external.Uri)
+	assert.Equal(t, "OpenGraph & title", external.Title)
+	assert.Equal(t, "OpenGraph description", external.Description)
+	if assert.NotNil(t, external.Thumb) {
+		assert.Equal(t, "image/png", external.Thumb.MimeType)
+	}
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.repo.uploadBlob"))
+}
+
+// Tests falling back to the plain HTML metadata and skipping broken thumbnails.
+func TestLinkCardFallbacks(t *testing.T) {
+	server := newLinkCardServer(t)
+	mockTransport := newUploadBlobRoundTripper()
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	embed, err := c.LinkCard(context.Background(), server.Client(), server.URL+"/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "Plain title", embed.EmbedExternal.External.Title)
+	assert.Equal(t, "Plain description", embed.EmbedExternal.External.Description)
+	assert.Nil(t, embed.EmbedExternal.External.Thumb)
+
+	embed, err = c.LinkCard(context.Background(), server.Client(), server.URL+"/broken-thumb")
+	assert.NoError(t, err)
+	assert.Equal(t, "Title", embed.EmbedExternal.External.Title)
+	assert.Nil(t, embed.EmbedExternal.External.Thumb)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.repo.uploadBlob"))
+}
+
+// Tests that pages which cannot be made into a card are reported.
+func TestLinkCardErrors(t *testing.T) {
+	server := newLinkCardServer(t)
+	c := newMockClient(t, newUploadBlobRoundTripper())
+	defer c.Close()
+
+	_, err := c.LinkCard(context.Background(), server.Client(), "not a uri")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = c.LinkCard(context.Background(), server.Client(), server.URL+"/missing")
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "404"))
+	}
+
+	_, err = c.LinkCard(context.Background(), server.Client(), server.URL+"/json")
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "application/json"))
+	}
+}
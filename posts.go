@@ -0,0 +1,144 @@
+package bluesky
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/rivo/uniseg"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// postCollection is the NSID of post records.
+	postCollection = "app.bsky.feed.post"
+
+	// postMaxTextBytes is the longest post text allowed by the lexicon.
+	postMaxTextBytes = 3000
+
+	// postMaxGraphemes is the longest post text allowed by the lexicon, counted
+	// in user perceived characters.
+	postMaxGraphemes = 300
+
+	// postMaxLangs is the most languages a post may be tagged with.
+	postMaxLangs = 3
+
+	// postMaxTags is the most additional hashtags a post may carry.
+	postMaxTags = 8
+)
+
+// Post is the content of a new post.
+type Post struct {
+	Text      string                // Primary content, may only be empty if there is an embed
+	Facets    []*bsky.RichtextFacet // Mentions, links and hashtags within the text
+	Langs     []string              // BCP-47 languages of the text, at most 3
+	Tags      []string              // Additional hashtags not in the text, at most 8
+	Embed     *bsky.FeedPost_Embed  // Images, video, link card or quoted record
+	CreatedAt time.Time             // Creation time, defaulting to now
+}
+
+func (c *client) CreatePost(ctx context.Context, post *Post) (*atproto.RepoStrongRef, error) {
+	return c.createPost(ctx, post, nil)
+}
+
+func (c *client) Reply(ctx context.Context, parent string, post *Post) (*atproto.RepoStrongRef, error) {
+	parentURI, err := parseRecordURI("com.atproto.repo.createRecord", parent, postCollection)
+	if err != nil {
+		return nil, err
+	}
+	// The thread root is the parent's root, or the parent itself if it is one
+	out, err := c.getRecord(ctx, parentURI)
+	if err != nil {
+		log.Err(err).Msg("Failed to fetch the post to reply to.")
+		return nil, err
+	}
+	if out.Cid == nil {
+		return nil, fmt.Errorf("post to reply to has no CID: %s", parent)
+	}
+	reply := &bsky.FeedPost_ReplyRef{
+		Parent: &atproto.RepoStrongRef{Uri: out.Uri, Cid: *out.Cid},
+		Root:   &atproto.RepoStrongRef{Uri: out.Uri, Cid: *out.Cid},
+	}
+	if record, ok := out.Value.Val.(*bsky.FeedPost); ok && record.Reply != nil && record.Reply.Root != nil {
+		reply.Root = record.Reply.Root
+	}
+	return c.createPost(ctx, post, reply)
+}
+
+func (c *client) DeletePost(ctx context.Context, uri string) error {
+	parsed, err := parseRecordURI("com.atproto.repo.deleteRecord", uri, postCollection)
+	if err != nil {
+		return err
+	}
+	if !c.ownsRepo(parsed.Authority()) {
+		return &ValidationError{Method: "com.atproto.repo.deleteRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: "must point to a post of the logged in account"}}}
+	}
+	if err := c.deleteRecord(ctx, postCollection, parsed.RecordKey().String()); err != nil {
+		log.Err(err).Msg("Failed to delete post.")
+		return err
+	}
+	return nil
+}
+
+// createPost validates a post and stores it in the client's repository,
+// optionally as a reply.
+func (c *client) createPost(ctx context.Context, post *Post, reply *bsky.FeedPost_ReplyRef) (*atproto.RepoStrongRef, error) {
+	if err := validatePost(post); err != nil {
+		return nil, err
+	}
+	createdAt := post.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = c.clock.Now()
+	}
+	record := &bsky.FeedPost{
+		LexiconTypeID: postCollection,
+		Text:          post.Text,
+		Facets:        post.Facets,
+		Langs:         post.Langs,
+		Tags:          post.Tags,
+		Embed:         post.Embed,
+		Reply:         reply,
+		CreatedAt:     createdAt.UTC().Format(syntax.AtprotoDatetimeLayout),
+	}
+	ref, err := c.createRecord(ctx, postCollection, record)
+	if err != nil {
+		log.Err(err).Msg("Failed to create post.")
+		return nil, err
+	}
+	return ref, nil
+}
+
+// validatePost checks a post against the app.bsky.feed.post lexicon, returning
+// a *ValidationError listing every violation.
+func validatePost(post *Post) error {
+	verr := &ValidationError{Method: "com.atproto.repo.createRecord"}
+	if post == nil {
+		post = &Post{}
+	}
+	if post.Text == "" && post.Embed == nil {
+		verr.Fields = append(verr.Fields, FieldError{Field: "Text", Param: "text", Value: post.Text, Reason: "is required without an embed"})
+	}
+	if len(post.Text) > postMaxTextBytes {
+		verr.Fields = append(verr.Fields, FieldError{Field: "Text", Param: "text", Value: post.Text, Reason: fmt.Sprintf("must be at most %d bytes long", postMaxTextBytes)})
+	} else if n := uniseg.GraphemeClusterCount(post.Text); n > postMaxGraphemes {
+		verr.Fields = append(verr.Fields, FieldError{Field: "Text", Param: "text", Value: post.Text, Reason: fmt.Sprintf("must be at most %d characters long, got %d", postMaxGraphemes, n)})
+	}
+	if len(post.Langs) > postMaxLangs {
+		verr.Fields = append(verr.Fields, FieldError{Field: "Langs", Param: "langs", Value: post.Langs, Reason: fmt.Sprintf("must have at most %d elements", postMaxLangs)})
+	}
+	for i, lang := range post.Langs {
+		if _, err := syntax.ParseLanguage(lang); err != nil {
+			verr.Fields = append(verr.Fields, FieldError{Field: "Langs", Param: "langs", Value: post.Langs, Reason: fmt.Sprintf("element %d must be a valid language: %v", i, err)})
+		}
+	}
+	if len(post.Tags) > postMaxTags {
+		verr.Fields = append(verr.Fields, FieldError{Field: "Tags", Param: "tags", Value: post.Tags, Reason: fmt.Sprintf("must have at most %d elements", postMaxTags)})
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
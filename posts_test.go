@@ -0,0 +1,174 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a mock transport storing created records and serving them back,
+// keyed by AT-URI.
+func newRepoRoundTripper(records map[string]string) *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.createRecord"] = func(req *http.Request) *http.Response {
+		var input struct {
+			Collection string          `json:"collection"`
+			Repo       string          `json:"repo"`
+			Record     json.RawMessage `json:"record"`
+		}
+		json.NewDecoder(req.Body).Decode(&input)
+
+		uri := "at://" + input.Repo + "/" + input.Collection + "/" + string(rune('a'+len(records)))
+		records[uri] = string(input.Record)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"uri": "` + uri + `", "cid": "cid-` + uri + `"}`)),
+		}
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.getRecord"] = func(req *http.Request) *http.Response {
+		query := req.URL.Query()
+		uri := "at://" + query.Get("repo") + "/" + query.Get("collection") + "/" + query.Get("rkey")
+		record, ok := records[uri]
+		if !ok {
+			return &http.Response{
+				StatusCode: 400,
+				Body:       io.NopCloser(strings.NewReader(`{"error": "RecordNotFound"}`)),
+			}
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"uri": "` + uri + `", "cid": "cid-` + uri + `", "value": ` + record + `}`)),
+		}
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.deleteRecord"] = func(req *http.Request) *http.Response {
+		var input struct {
+			Collection string `json:"collection"`
+			Repo       string `json:"repo"`
+			Rkey       string `json:"rkey"`
+		}
+		json.NewDecoder(req.Body).Decode(&input)
+		delete(records, "at://"+input.Repo+"/"+input.Collection+"/"+input.Rkey)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{}`)),
+		}
+	}
+	return mockTransport
+}
+
+// Tests that posts are created in the logged in account's repository with all
+// fields of the record set.
+func TestCreatePost(t *testing.T) {
+	clock := &mockClock{time: time.Date(2025, 2, 8, 18, 7, 5, 0, time.UTC)}
+	records := make(map[string]string)
+	mockTransport := newRepoRoundTripper(records)
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppKey",
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	defer c.Close()
+
+	ref, err := c.CreatePost(context.Background(), &Post{Text: "Where's my pineapple?", Langs: []string{"en"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "at://did:plc:test/app.bsky.feed.post/a", ref.Uri)
+	assert.Equal(t, "cid-at://did:plc:test/app.bsky.feed.post/a", ref.Cid)
+	assert.JSONEq(t, `{
+		"$type": "app.bsky.feed.post",
+		"text": "Where's my pineapple?",
+		"langs": ["en"],
+		"createdAt": "2025-02-08T18:07:05Z"
+	}`, records[ref.Uri])
+}
+
+// Tests that replies reference their parent and the root of its thread.
+func TestReply(t *testing.T) {
+	records := make(map[string]string)
+	mockTransport := newRepoRoundTripper(records)
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	root, err := c.CreatePost(context.Background(), &Post{Text: "root"})
+	assert.NoError(t, err)
+	parent, err := c.Reply(context.Background(), root.Uri, &Post{Text: "parent"})
+	assert.NoError(t, err)
+	reply, err := c.Reply(context.Background(), parent.Uri, &Post{Text: "reply"})
+	assert.NoError(t, err)
+
+	var record struct {
+		Reply struct {
+			Root   map[string]string `json:"root"`
+			Parent map[string]string `json:"parent"`
+		} `json:"reply"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(records[reply.Uri]), &record))
+	assert.Equal(t, root.Uri, record.Reply.Root["uri"])
+	assert.Equal(t, root.Cid, record.Reply.Root["cid"])
+	assert.Equal(t, parent.Uri, record.Reply.Parent["uri"])
+	assert.Equal(t, parent.Cid, record.Reply.Parent["cid"])
+
+	_, err = c.Reply(context.Background(), "at://did:plc:test/app.bsky.feed.like/a", &Post{Text: "reply"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// Tests deleting posts, which is only allowed for the account's own posts.
+func TestDeletePost(t *testing.T) {
+	records := make(map[string]string)
+	mockTransport := newRepoRoundTripper(records)
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	ref, err := c.CreatePost(context.Background(), &Post{Text: "oops"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeletePost(context.Background(), ref.Uri))
+	assert.Empty(t, records)
+
+	// Posts of the logged in account may also be given by its handle
+	ref, err = c.CreatePost(context.Background(), &Post{Text: "oops again"})
+	assert.NoError(t, err)
+	rkey := ref.Uri[strings.LastIndex(ref.Uri, "/")+1:]
+	assert.NoError(t, c.DeletePost(context.Background(), "at://Test.bsky.social/app.bsky.feed.post/"+rkey))
+	assert.Empty(t, records)
+
+	err = c.DeletePost(context.Background(), "at://did:plc:other/app.bsky.feed.post/a")
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, "com.atproto.repo.deleteRecord", verr.Method)
+	}
+	err = c.DeletePost(context.Background(), "at://other.bsky.social/app.bsky.feed.post/a")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.repo.deleteRecord"))
+}
+
+// Tests that posts violating the lexicon are rejected with every violation.
+func TestCreatePostValidation(t *testing.T) {
+	mockTransport := newRepoRoundTripper(make(map[string]string))
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	_, err := c.CreatePost(context.Background(), &Post{Langs: []string{"en", "de", "fr", "??"}, Tags: make([]string, 9)})
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, "com.atproto.repo.createRecord", verr.Method)
+		var params []string
+		for _, field := range verr.Fields {
+			params = append(params, field.Param)
+		}
+		assert.Equal(t, []string{"text", "langs", "langs", "tags"}, params)
+	}
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.repo.createRecord"))
+}
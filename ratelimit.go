@@ -0,0 +1,98 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+// rateLimitMinWait is the shortest time to wait before retrying a throttled
+// query. The reset times sent by the server only have second precision, so
+// retrying right away would likely be throttled again.
+var rateLimitMinWait = time.Second
+
+// RateLimitError is returned from any API call the server rejected because the
+// client went over one of its rate limits. It carries the limit details from
+// the RateLimit-* response headers, any of which might be missing if the server
+// did not send them. https://docs.bsky.app/docs/advanced-guides/rate-limits
+//
+// RateLimitError matches ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	Limit     int       // Number of requests permitted in the policy window
+	Remaining int       // Number of requests left in the current window
+	Reset     time.Time // Time when the current window ends and the budget resets
+	Policy    string    // Raw policy definition, e.g. "3000;w=300"
+
+	Err error // Original error returned by the server
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("%v: %v", ErrRateLimited, e.Err)
+	}
+	return fmt.Sprintf("%v until %v: %v", ErrRateLimited, e.Reset, e.Err)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// WithRateLimitRetry makes the client wait out rate limits on idempotent
+// queries (e.g. app.bsky.feed.searchPosts) and retry them once the limit resets,
+// instead of failing with a RateLimitError. Calls are only held back if the
+// limit resets within maxWait, otherwise the error is returned right away.
+// Procedures, which might not be safe to repeat, are never retried.
+func WithRateLimitRetry(maxWait time.Duration) clientOption {
+	return func(params *clientOptionalParams) {
+		params.rateLimitMaxWait = maxWait
+	}
+}
+
+// asRateLimitError converts a throttling error from the XRPC transport into a
+// RateLimitError, passing any other error through untouched.
+func asRateLimitError(err error) error {
+	var xrpcErr *xrpc.Error
+	if !errors.As(err, &xrpcErr) || !xrpcErr.IsThrottled() {
+		return err
+	}
+	rlErr := &RateLimitError{Err: err}
+	if info := xrpcErr.Ratelimit; info != nil {
+		rlErr.Limit = info.Limit
+		rlErr.Remaining = info.Remaining
+		rlErr.Reset = info.Reset
+		rlErr.Policy = info.Policy
+	}
+	return rlErr
+}
+
+// waitRateLimit blocks until the rate limit reported by err resets, as long as
+// that happens before the deadline. It returns an error if the caller should
+// give up instead of retrying.
+func (c *client) waitRateLimit(ctx context.Context, err *RateLimitError, deadline time.Time) error {
+	if err.Reset.IsZero() {
+		return err
+	}
+	wait := max(err.Reset.Sub(c.clock.Now()), rateLimitMinWait)
+	if c.clock.Now().Add(wait).After(deadline) {
+		return err
+	}
+	log.Warn().Msgf("Rate limited by server, retrying in %v.", wait)
+
+	timer := c.clock.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a throttled XRPC response with the given rate limit headers.
+func getRateLimitedResponse(limit int, remaining int, reset time.Time, policy string) *http.Response {
+	header := make(http.Header)
+	header.Set("RateLimit-Limit", fmt.Sprint(limit))
+	header.Set("RateLimit-Remaining", fmt.Sprint(remaining))
+	header.Set("RateLimit-Reset", fmt.Sprint(reset.Unix()))
+	header.Set("RateLimit-Policy", policy)
+
+	return &http.Response{
+		StatusCode: 429,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{"error": "RateLimitExceeded", "message": "Rate Limit Exceeded"}`)),
+	}
+}
+
+// Tests that throttled calls fail with a RateLimitError carrying the limits
+// reported by the server.
+func TestRateLimitError(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/app.bsky.feed.searchPosts"] = getRateLimitedResponse(3000, 0, reset, "3000;w=300")
+
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	_, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) {
+		t.Fatalf("expected a RateLimitError, got: %v", err)
+	}
+	assert.Equal(t, 3000, rlErr.Limit)
+	assert.Equal(t, 0, rlErr.Remaining)
+	assert.Equal(t, reset, rlErr.Reset)
+	assert.Equal(t, "3000;w=300", rlErr.Policy)
+}
+
+// Tests that a throttled login is reported as such and not as bad credentials.
+func TestRateLimitedLogin(t *testing.T) {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/com.atproto.server.createSession"] = getRateLimitedResponse(30, 0, time.Now().Add(time.Minute), "30;w=300")
+
+	_, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey", withXrpcClient(&xrpc.Client{
+		Client: &http.Client{
+			Transport: mockTransport,
+		},
+		Host: ServerBskySocial,
+	}))
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.NotErrorIs(t, err, ErrLoginUnauthorized)
+}
+
+// Tests that throttled queries are retried once the limit resets if the client
+// was configured to do so.
+func TestRateLimitRetry(t *testing.T) {
+	throttled := true
+
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.searchPosts"] = func(req *http.Request) *http.Response {
+		if throttled {
+			throttled = false
+			return getRateLimitedResponse(3000, 0, time.Now(), "3000;w=300")
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"posts": [%s]}`, getPostStr("at://a")))),
+		}
+	}
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey",
+		WithRateLimitRetry(time.Minute),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	defer c.Close()
+
+	out, err := c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(out.Posts))
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
+
+// Tests that throttled queries are not retried if the limit resets too far in
+// the future.
+func TestRateLimitRetryTooLong(t *testing.T) {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseMap["/xrpc/app.bsky.feed.searchPosts"] = getRateLimitedResponse(3000, 0, time.Now().Add(time.Hour), "3000;w=300")
+
+	c, err := NewClient(context.Background(), ServerBskySocial, "testHandle", "testAppkey",
+		WithRateLimitRetry(time.Minute),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: mockTransport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "peterman"})
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+}
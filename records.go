@@ -0,0 +1,207 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+// listRecordsMaxLimit is the largest page com.atproto.repo.listRecords serves.
+const listRecordsMaxLimit = 100
+
+// Record is a record of a repository, decoded into one of indigo's lexicon
+// types, e.g. Record[*bsky.FeedLike] for a like.
+type Record[T util.CBOR] struct {
+	Uri   string
+	Cid   string
+	Value T
+}
+
+// rawRecord is a record as served by com.atproto.repo.getRecord and
+// com.atproto.repo.listRecords, with its value left for decodeRecord.
+type rawRecord struct {
+	Uri   string          `json:"uri"`
+	Cid   *string         `json:"cid,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// rawRecordPage is a page of com.atproto.repo.listRecords.
+type rawRecordPage struct {
+	Cursor  *string      `json:"cursor,omitempty"`
+	Records []*rawRecord `json:"records"`
+}
+
+// GetRecord fetches the record at an AT-URI from any repository. The collection
+// of the AT-URI must match T, e.g. GetRecord[*bsky.GraphFollow] for a follow.
+func GetRecord[T util.CBOR](ctx context.Context, c Client, uri string) (*Record[T], error) {
+	impl, collection, err := recordClient[T](c)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseRecordURI("com.atproto.repo.getRecord", uri, collection)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"repo":       parsed.Authority().String(),
+		"collection": collection,
+		"rkey":       parsed.RecordKey().String(),
+	}
+	var out rawRecord
+	if err := impl.do(ctx, xrpc.Query, "", "com.atproto.repo.getRecord", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to get record.")
+		return nil, err
+	}
+	return decodeRecord[T](collection, &out)
+}
+
+// ListRecords iterates over the records of type T in a repository, given by
+// handle or DID, or in the logged in account's repository if repo is empty.
+func ListRecords[T util.CBOR](ctx context.Context, c Client, repo string, maxResults int) *Iterator[*Record[T]] {
+	impl, collection, err := recordClient[T](c)
+	if err == nil {
+		if repo == "" {
+			repo = impl.did()
+		} else if _, perr := syntax.ParseAtIdentifier(repo); perr != nil {
+			err = &ValidationError{Method: "com.atproto.repo.listRecords", Fields: []FieldError{{Field: "repo", Param: "repo", Value: repo, Reason: fmt.Sprintf("must be a valid at-identifier: %v", perr)}}}
+		}
+	}
+	fetch := func(ctx context.Context, cursor string) ([]*Record[T], string, error) {
+		if err != nil {
+			return nil, "", err
+		}
+		params := map[string]interface{}{
+			"repo":       repo,
+			"collection": collection,
+			"limit":      listRecordsMaxLimit,
+		}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var out rawRecordPage
+		if err := impl.do(ctx, xrpc.Query, "", "com.atproto.repo.listRecords", params, nil, &out); err != nil {
+			log.Err(err).Msg("Failed to list records.")
+			return nil, "", err
+		}
+		records := make([]*Record[T], 0, len(out.Records))
+		for _, raw := range out.Records {
+			record, err := decodeRecord[T](collection, raw)
+			if err != nil {
+				return nil, "", err
+			}
+			records = append(records, record)
+		}
+		if out.Cursor == nil {
+			return records, "", nil
+		}
+		return records, *out.Cursor, nil
+	}
+	key := func(record *Record[T]) string {
+		return record.Uri
+	}
+	return newIterator(ctx, "", maxResults, fetch, key)
+}
+
+// PutRecord creates or replaces the record with the given key in the logged in
+// account's repository, in the collection matching T.
+func PutRecord[T util.CBOR](ctx context.Context, c Client, rkey string, record T) (*atproto.RepoStrongRef, error) {
+	impl, collection, err := recordClient[T](c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syntax.ParseRecordKey(rkey); err != nil {
+		return nil, &ValidationError{Method: "com.atproto.repo.putRecord", Fields: []FieldError{{Field: "rkey", Param: "rkey", Value: rkey, Reason: fmt.Sprintf("must be a valid record key: %v", err)}}}
+	}
+	if reason := checkRecordValue(record); reason != "" {
+		return nil, &ValidationError{Method: "com.atproto.repo.putRecord", Fields: []FieldError{{Field: "record", Param: "record", Value: nil, Reason: reason}}}
+	}
+	input := &atproto.RepoPutRecord_Input{
+		Collection: collection,
+		Repo:       impl.did(),
+		Rkey:       rkey,
+		Record:     &util.LexiconTypeDecoder{Val: record},
+	}
+	var out atproto.RepoPutRecord_Output
+	if err := impl.do(ctx, xrpc.Procedure, "application/json", "com.atproto.repo.putRecord", nil, input, &out); err != nil {
+		log.Err(err).Msg("Failed to put record.")
+		return nil, err
+	}
+	return &atproto.RepoStrongRef{Uri: out.Uri, Cid: out.Cid}, nil
+}
+
+func (c *client) DeleteRecord(ctx context.Context, uri string) error {
+	parsed, err := syntax.ParseATURI(uri)
+	if err == nil {
+		_, err = syntax.ParseNSID(parsed.Collection().String())
+	}
+	if err != nil || parsed.RecordKey() == "" {
+		return &ValidationError{Method: "com.atproto.repo.deleteRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: "must be a valid at-uri of a record"}}}
+	}
+	if !c.ownsRepo(parsed.Authority()) {
+		return &ValidationError{Method: "com.atproto.repo.deleteRecord", Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: "must point to a record of the logged in account"}}}
+	}
+	if err := c.deleteRecord(ctx, parsed.Collection().String(), parsed.RecordKey().String()); err != nil {
+		log.Err(err).Msg("Failed to delete record.")
+		return err
+	}
+	return nil
+}
+
+// recordClient returns the implementation behind c, which the generic record
+// functions need as Go doesn't allow generic methods, along with the NSID of
+// the collection holding records of type T.
+func recordClient[T util.CBOR](c Client) (*client, string, error) {
+	impl, ok := c.(*client)
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported client implementation %T", c)
+	}
+	if reason := checkRecordValue(newRecord[T]()); reason != "" {
+		return nil, "", fmt.Errorf("%T is not a lexicon record type: %s", *new(T), reason)
+	}
+	collection, err := recordType(newRecord[T]())
+	if err != nil {
+		return nil, "", fmt.Errorf("%T is not a lexicon record type: %w", *new(T), err)
+	}
+	if _, err := syntax.ParseNSID(collection); err != nil {
+		return nil, "", fmt.Errorf("%T is not a lexicon record type: %w", *new(T), err)
+	}
+	return impl, collection, nil
+}
+
+// decodeRecord decodes a record served by the server into T, checking that its
+// $type matches the collection.
+func decodeRecord[T util.CBOR](collection string, raw *rawRecord) (*Record[T], error) {
+	typ, err := util.TypeExtract(raw.Value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding record %s: %w", raw.Uri, err)
+	}
+	if typ != collection {
+		return nil, fmt.Errorf("decoding record %s: unexpected $type %s, want %s", raw.Uri, typ, collection)
+	}
+	value := newRecord[T]()
+	if err := json.Unmarshal(raw.Value, value); err != nil {
+		return nil, fmt.Errorf("decoding record %s: %w", raw.Uri, err)
+	}
+	record := &Record[T]{Uri: raw.Uri, Value: value}
+	if raw.Cid != nil {
+		record.Cid = *raw.Cid
+	}
+	return record, nil
+}
+
+// newRecord allocates an empty record of type T, which indigo's record types
+// implement as struct pointers.
+func newRecord[T util.CBOR]() T {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Pointer {
+		return *new(T)
+	}
+	return reflect.New(typ.Elem()).Interface().(T)
+}
@@ -0,0 +1,178 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a mock transport serving the records of a repository, extended with
+// putRecord and listRecords. Pages of listRecords hold two records to exercise
+// pagination.
+func newRecordsRoundTripper(records map[string]string) *mockRoundTripper {
+	mockTransport := newRepoRoundTripper(records)
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.putRecord"] = func(req *http.Request) *http.Response {
+		var input struct {
+			Collection string          `json:"collection"`
+			Repo       string          `json:"repo"`
+			Rkey       string          `json:"rkey"`
+			Record     json.RawMessage `json:"record"`
+		}
+		json.NewDecoder(req.Body).Decode(&input)
+
+		uri := "at://" + input.Repo + "/" + input.Collection + "/" + input.Rkey
+		records[uri] = string(input.Record)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"uri": "` + uri + `", "cid": "cid-` + uri + `"}`)),
+		}
+	}
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.listRecords"] = func(req *http.Request) *http.Response {
+		query := req.URL.Query()
+		prefix := "at://" + query.Get("repo") + "/" + query.Get("collection") + "/"
+
+		var uris []string
+		for uri := range records {
+			if strings.HasPrefix(uri, prefix) {
+				uris = append(uris, uri)
+			}
+		}
+		sort.Strings(uris)
+
+		start, _ := strconv.Atoi(query.Get("cursor"))
+		end := min(start+2, len(uris))
+		var page []string
+		for _, uri := range uris[start:end] {
+			page = append(page, `{"uri": "`+uri+`", "cid": "cid-`+uri+`", "value": `+records[uri]+`}`)
+		}
+		cursor := ""
+		if end < len(uris) {
+			cursor = fmt.Sprintf(`"cursor": "%d", `, end)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{` + cursor + `"records": [` + strings.Join(page, ",") + `]}`)),
+		}
+	}
+	return mockTransport
+}
+
+// valueRecord is a CBOR type with value receivers, which is not a lexicon
+// record type.
+type valueRecord struct{}
+
+func (valueRecord) MarshalCBOR(io.Writer) error   { return nil }
+func (valueRecord) UnmarshalCBOR(io.Reader) error { return nil }
+
+// Tests storing and fetching records of various types.
+func TestPutAndGetRecord(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newRecordsRoundTripper(records))
+	defer c.Close()
+
+	name := "Test"
+	ref, err := PutRecord(context.Background(), c, "self", &bsky.ActorProfile{DisplayName: &name})
+	assert.NoError(t, err)
+	assert.Equal(t, "at://did:plc:test/app.bsky.actor.profile/self", ref.Uri)
+
+	profile, err := GetRecord[*bsky.ActorProfile](context.Background(), c, ref.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, ref.Uri, profile.Uri)
+	assert.Equal(t, ref.Cid, profile.Cid)
+	assert.Equal(t, "Test", *profile.Value.DisplayName)
+
+	_, err = PutRecord(context.Background(), c, "3l4m5n6o7p8q9", &bsky.GraphFollow{Subject: "did:plc:other", CreatedAt: "2025-02-08T18:07:05Z"})
+	assert.NoError(t, err)
+	follow, err := GetRecord[*bsky.GraphFollow](context.Background(), c, "at://did:plc:test/app.bsky.graph.follow/3l4m5n6o7p8q9")
+	assert.NoError(t, err)
+	assert.Equal(t, "did:plc:other", follow.Value.Subject)
+
+	// Records are only decoded into the type of their collection
+	_, err = GetRecord[*bsky.GraphBlock](context.Background(), c, "at://did:plc:test/app.bsky.graph.follow/3l4m5n6o7p8q9")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	records["at://did:plc:test/app.bsky.graph.block/mislabeled"] = records["at://did:plc:test/app.bsky.graph.follow/3l4m5n6o7p8q9"]
+	_, err = GetRecord[*bsky.GraphBlock](context.Background(), c, "at://did:plc:test/app.bsky.graph.block/mislabeled")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unexpected $type app.bsky.graph.follow")
+	}
+
+	_, err = PutRecord(context.Background(), c, "not/a/key", &bsky.ActorProfile{})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = GetRecord[util.CBOR](context.Background(), c, ref.Uri)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not a lexicon record type")
+	}
+	_, err = PutRecord(context.Background(), c, "self", valueRecord{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not a lexicon record type")
+	}
+	_, err = PutRecord[*bsky.ActorProfile](context.Background(), c, "self", nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// Tests paging through the records of a collection.
+func TestListRecords(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newRecordsRoundTripper(records))
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := PutRecord(context.Background(), c, fmt.Sprintf("like%d", i), &bsky.FeedLike{CreatedAt: "2025-02-08T18:07:05Z"})
+		assert.NoError(t, err)
+	}
+	_, err := PutRecord(context.Background(), c, "self", &bsky.ActorProfile{})
+	assert.NoError(t, err)
+
+	var rkeys []string
+	it := ListRecords[*bsky.FeedLike](context.Background(), c, "", 0)
+	for it.Next() {
+		assert.Equal(t, "2025-02-08T18:07:05Z", it.Item().Value.CreatedAt)
+		rkeys = append(rkeys, it.Item().Uri[strings.LastIndex(it.Item().Uri, "/")+1:])
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"like0", "like1", "like2", "like3", "like4"}, rkeys)
+
+	it = ListRecords[*bsky.FeedLike](context.Background(), c, "did:plc:test", 3)
+	for it.Next() {
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, "4", it.Cursor())
+
+	it = ListRecords[*bsky.FeedLike](context.Background(), c, "not an identifier", 0)
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), ErrInvalidRequest)
+}
+
+// Tests that only records of the logged in account can be deleted.
+func TestDeleteRecord(t *testing.T) {
+	records := make(map[string]string)
+	mockTransport := newRecordsRoundTripper(records)
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	ref, err := PutRecord(context.Background(), c, "block", &bsky.GraphBlock{Subject: "did:plc:other", CreatedAt: "2025-02-08T18:07:05Z"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeleteRecord(context.Background(), ref.Uri))
+	assert.Empty(t, records)
+
+	_, err = PutRecord(context.Background(), c, "block", &bsky.GraphBlock{Subject: "did:plc:other", CreatedAt: "2025-02-08T18:07:05Z"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeleteRecord(context.Background(), "at://test.bsky.social/app.bsky.graph.block/block"))
+	assert.Empty(t, records)
+
+	assert.ErrorIs(t, c.DeleteRecord(context.Background(), "at://did:plc:other/app.bsky.graph.block/block"), ErrInvalidRequest)
+	assert.ErrorIs(t, c.DeleteRecord(context.Background(), "at://other.bsky.social/app.bsky.graph.block/block"), ErrInvalidRequest)
+	assert.ErrorIs(t, c.DeleteRecord(context.Background(), "at://did:plc:test/app.bsky.graph.block"), ErrInvalidRequest)
+	assert.ErrorIs(t, c.DeleteRecord(context.Background(), "https://bsky.app"), ErrInvalidRequest)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.repo.deleteRecord"))
+}
@@ -0,0 +1,107 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// did returns the DID of the account the client is logged in to.
+func (c *client) did() string {
+	return c.session.Load().auth.Did
+}
+
+// ownsRepo reports whether an AT-URI authority names the logged in account's
+// repository, by its DID or its case-insensitive handle.
+func (c *client) ownsRepo(authority syntax.AtIdentifier) bool {
+	auth := c.session.Load().auth
+	if handle, err := authority.AsHandle(); err == nil {
+		return handle.Normalize() == syntax.Handle(auth.Handle).Normalize()
+	}
+	return authority.String() == auth.Did
+}
+
+// createRecord stores a new record in a collection of the client's repository,
+// returning its AT-URI and CID.
+func (c *client) createRecord(ctx context.Context, collection string, record util.CBOR) (*atproto.RepoStrongRef, error) {
+	input := &atproto.RepoCreateRecord_Input{
+		Collection: collection,
+		Repo:       c.did(),
+		Record:     &util.LexiconTypeDecoder{Val: record},
+	}
+	var out atproto.RepoCreateRecord_Output
+	if err := c.do(ctx, xrpc.Procedure, "application/json", "com.atproto.repo.createRecord", nil, input, &out); err != nil {
+		return nil, err
+	}
+	return &atproto.RepoStrongRef{Uri: out.Uri, Cid: out.Cid}, nil
+}
+
+// deleteRecord removes a record from a collection of the client's repository.
+func (c *client) deleteRecord(ctx context.Context, collection string, rkey string) error {
+	input := &atproto.RepoDeleteRecord_Input{
+		Collection: collection,
+		Repo:       c.did(),
+		Rkey:       rkey,
+	}
+	return c.do(ctx, xrpc.Procedure, "application/json", "com.atproto.repo.deleteRecord", nil, input, nil)
+}
+
+// getRecord fetches the record at an AT-URI from any repository.
+func (c *client) getRecord(ctx context.Context, uri syntax.ATURI) (*atproto.RepoGetRecord_Output, error) {
+	params := map[string]interface{}{
+		"repo":       uri.Authority().String(),
+		"collection": uri.Collection().String(),
+		"rkey":       uri.RecordKey().String(),
+	}
+	var out atproto.RepoGetRecord_Output
+	if err := c.do(ctx, xrpc.Query, "", "com.atproto.repo.getRecord", params, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// checkRecordValue returns why a value can't be sent as a record, or an empty
+// string if it can. indigo's record types are struct pointers, and encoding
+// anything else, including nil pointers, panics.
+func checkRecordValue(record util.CBOR) string {
+	value := reflect.ValueOf(record)
+	switch {
+	case !value.IsValid() || (value.Kind() == reflect.Pointer && value.IsNil()):
+		return "must have a record value"
+	case value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct:
+		return "must be a pointer to a lexicon record"
+	}
+	return ""
+}
+
+// recordType returns the $type of a lexicon record, as it will be sent to the
+// server. The record must have passed checkRecordValue.
+func recordType(record util.CBOR) (string, error) {
+	data, err := json.Marshal(&util.LexiconTypeDecoder{Val: record})
+	if err != nil {
+		return "", err
+	}
+	return util.TypeExtract(data)
+}
+
+// parseRecordURI parses the AT-URI of a record in a collection, returning a
+// *ValidationError for the given method if it is malformed or points elsewhere.
+func parseRecordURI(method string, uri string, collection string) (syntax.ATURI, error) {
+	invalid := func(reason string) error {
+		return &ValidationError{Method: method, Fields: []FieldError{{Field: "uri", Param: "uri", Value: uri, Reason: reason}}}
+	}
+	parsed, err := syntax.ParseATURI(uri)
+	if err != nil {
+		return "", invalid(fmt.Sprintf("must be a valid at-uri: %v", err))
+	}
+	if parsed.Collection().String() != collection || parsed.RecordKey() == "" {
+		return "", invalid("must point to a record in " + collection)
+	}
+	return parsed, nil
+}
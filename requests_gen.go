@@ -0,0 +1,328 @@
+// Code generated by genrequests from the lexicons directory. DO NOT EDIT.
+
+package bluesky
+
+import (
+	"context"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+// SearchPostsRequest holds the parameters of app.bsky.feed.searchPosts.
+// Fields are validated against the lexicon before the request is sent.
+type SearchPostsRequest struct {
+	// Filter to posts by the given account. Handles are resolved to DID before
+	// query-time.
+	Author string `xrpc:"author,omitempty,format=at-identifier"`
+
+	// Optional pagination mechanism; may not necessarily allow scrolling through
+	// entire result set.
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Filter to posts with URLs (facet links or embeds) linking to the given
+	// domain (hostname). Server may apply hostname normalization.
+	Domain string `xrpc:"domain,omitempty"`
+
+	// Filter to posts in the given language. Expected to be based on post language
+	// field, though server may override language detection.
+	Lang string `xrpc:"lang,omitempty,format=language"`
+
+	// Defaults to 25.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+
+	// Filter to posts which mention the given account. Handles are resolved to DID
+	// before query-time. Only matches rich-text facet mentions.
+	Mentions string `xrpc:"mentions,omitempty,format=at-identifier"`
+
+	// Search query string; syntax, phrase, boolean, and faceting is unspecified,
+	// but Lucene query syntax is recommended.
+	Q string `xrpc:"q,required"`
+
+	// Filter results for posts after the indicated datetime (inclusive). Expected
+	// to use 'sortAt' timestamp, which may not match 'createdAt'. Can be a
+	// datetime, or just an ISO date (YYYY-MM-DD).
+	Since time.Time `xrpc:"since,omitempty,datetime"`
+
+	// Specifies the ranking order of results. Defaults to latest.
+	Sort string `xrpc:"sort,omitempty,enum=top|latest"`
+
+	// Filter to posts with the given tag (hashtag), based on rich-text facet or
+	// tag field. Do not include the hash (#) prefix. Multiple tags can be
+	// specified, with 'AND' matching.
+	Tag []string `xrpc:"tag,omitempty"`
+
+	// Filter results for posts before the indicated datetime (not inclusive).
+	// Expected to use 'sortAt' timestamp, which may not match 'createdAt'. Can be
+	// a datetime, or just an ISO date (YYY-MM-DD).
+	Until time.Time `xrpc:"until,omitempty,datetime"`
+
+	// Filter to posts with links (facet links or embeds) pointing to this URL.
+	// Server may apply URL normalization or fuzzy matching.
+	Url string `xrpc:"url,omitempty,format=uri"`
+}
+
+// SearchPosts calls app.bsky.feed.searchPosts. Find posts matching search
+// criteria, returning views of those posts.
+func (c *client) SearchPosts(ctx context.Context, request *SearchPostsRequest) (*bsky.FeedSearchPosts_Output, error) {
+	if err := validateRequest("app.bsky.feed.searchPosts", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.FeedSearchPosts_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.feed.searchPosts", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.feed.searchPosts.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchActorsRequest holds the parameters of app.bsky.actor.searchActors.
+// Fields are validated against the lexicon before the request is sent.
+type SearchActorsRequest struct {
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Defaults to 25.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+
+	// Search query string. Syntax, phrase, boolean, and faceting is unspecified,
+	// but Lucene query syntax is recommended.
+	Q string `xrpc:"q,omitempty"`
+
+	// DEPRECATED: use 'q' instead.
+	Term string `xrpc:"term,omitempty"`
+}
+
+// SearchActors calls app.bsky.actor.searchActors. Find actors (profiles)
+// matching search criteria. Does not require auth.
+func (c *client) SearchActors(ctx context.Context, request *SearchActorsRequest) (*bsky.ActorSearchActors_Output, error) {
+	if err := validateRequest("app.bsky.actor.searchActors", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.ActorSearchActors_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.actor.searchActors", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.actor.searchActors.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchActorsTypeaheadRequest holds the parameters of app.bsky.actor.searchActorsTypeahead.
+// Fields are validated against the lexicon before the request is sent.
+type SearchActorsTypeaheadRequest struct {
+	// Defaults to 10.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+
+	// Search query prefix; not a full query string.
+	Q string `xrpc:"q,omitempty"`
+
+	// DEPRECATED: use 'q' instead.
+	Term string `xrpc:"term,omitempty"`
+}
+
+// SearchActorsTypeahead calls app.bsky.actor.searchActorsTypeahead. Find actor
+// suggestions for a prefix search term. Expected use is for auto-completion
+// during text field entry. Does not require auth.
+func (c *client) SearchActorsTypeahead(ctx context.Context, request *SearchActorsTypeaheadRequest) (*bsky.ActorSearchActorsTypeahead_Output, error) {
+	if err := validateRequest("app.bsky.actor.searchActorsTypeahead", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.ActorSearchActorsTypeahead_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.actor.searchActorsTypeahead", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.actor.searchActorsTypeahead.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GraphSearchStarterPacks_Output is the output of app.bsky.graph.searchStarterPacks.
+type GraphSearchStarterPacks_Output struct {
+	Cursor       *string                                `json:"cursor,omitempty"`
+	StarterPacks []*bsky.GraphDefs_StarterPackViewBasic `json:"starterPacks"`
+}
+
+// SearchStarterPacksRequest holds the parameters of app.bsky.graph.searchStarterPacks.
+// Fields are validated against the lexicon before the request is sent.
+type SearchStarterPacksRequest struct {
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Defaults to 25.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+
+	// Search query string. Syntax, phrase, boolean, and faceting is unspecified,
+	// but Lucene query syntax is recommended.
+	Q string `xrpc:"q,required"`
+}
+
+// SearchStarterPacks calls app.bsky.graph.searchStarterPacks. Find starter
+// packs matching search criteria. Does not require auth.
+func (c *client) SearchStarterPacks(ctx context.Context, request *SearchStarterPacksRequest) (*GraphSearchStarterPacks_Output, error) {
+	if err := validateRequest("app.bsky.graph.searchStarterPacks", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out GraphSearchStarterPacks_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.graph.searchStarterPacks", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.graph.searchStarterPacks.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPopularFeedGeneratorsRequest holds the parameters of app.bsky.unspecced.getPopularFeedGenerators.
+// Fields are validated against the lexicon before the request is sent.
+type GetPopularFeedGeneratorsRequest struct {
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Defaults to 50.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+
+	Query string `xrpc:"query,omitempty"`
+}
+
+// GetPopularFeedGenerators calls app.bsky.unspecced.getPopularFeedGenerators.
+// An unspecced view of globally popular feed generators.
+func (c *client) GetPopularFeedGenerators(ctx context.Context, request *GetPopularFeedGeneratorsRequest) (*bsky.UnspeccedGetPopularFeedGenerators_Output, error) {
+	if err := validateRequest("app.bsky.unspecced.getPopularFeedGenerators", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.UnspeccedGetPopularFeedGenerators_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.unspecced.getPopularFeedGenerators", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.unspecced.getPopularFeedGenerators.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTimelineRequest holds the parameters of app.bsky.feed.getTimeline.
+// Fields are validated against the lexicon before the request is sent.
+type GetTimelineRequest struct {
+	// Variant 'algorithm' for timeline. Implementation-specific. NOTE: most feed
+	// flexibility has been moved to feed generator mechanism.
+	Algorithm string `xrpc:"algorithm,omitempty"`
+
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Defaults to 50.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+}
+
+// GetTimeline calls app.bsky.feed.getTimeline. Get a view of the requesting
+// account's home timeline. This is expected to be some form of
+// reverse-chronological feed.
+func (c *client) GetTimeline(ctx context.Context, request *GetTimelineRequest) (*bsky.FeedGetTimeline_Output, error) {
+	if err := validateRequest("app.bsky.feed.getTimeline", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.FeedGetTimeline_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.feed.getTimeline", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.feed.getTimeline.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAuthorFeedRequest holds the parameters of app.bsky.feed.getAuthorFeed.
+// Fields are validated against the lexicon before the request is sent.
+type GetAuthorFeedRequest struct {
+	Actor string `xrpc:"actor,required,format=at-identifier"`
+
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	// Combinations of post/repost types to include in response. Defaults to
+	// posts_with_replies.
+	Filter string `xrpc:"filter,omitempty"`
+
+	// Defaults to false.
+	IncludePins bool `xrpc:"includePins,omitempty"`
+
+	// Defaults to 50.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+}
+
+// GetAuthorFeed calls app.bsky.feed.getAuthorFeed. Get a view of an actor's
+// 'author feed' (post and reposts by the author). Does not require auth.
+func (c *client) GetAuthorFeed(ctx context.Context, request *GetAuthorFeedRequest) (*bsky.FeedGetAuthorFeed_Output, error) {
+	if err := validateRequest("app.bsky.feed.getAuthorFeed", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.FeedGetAuthorFeed_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.feed.getAuthorFeed", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.feed.getAuthorFeed.")
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFeedRequest holds the parameters of app.bsky.feed.getFeed.
+// Fields are validated against the lexicon before the request is sent.
+type GetFeedRequest struct {
+	Cursor string `xrpc:"cursor,omitempty"`
+
+	Feed string `xrpc:"feed,required,format=at-uri"`
+
+	// Defaults to 50.
+	Limit int `xrpc:"limit,omitempty,minimum=1,maximum=100"`
+}
+
+// GetFeed calls app.bsky.feed.getFeed. Get a hydrated feed from an actor's
+// selected feed generator. Implemented by App View.
+func (c *client) GetFeed(ctx context.Context, request *GetFeedRequest) (*bsky.FeedGetFeed_Output, error) {
+	if err := validateRequest("app.bsky.feed.getFeed", request); err != nil {
+		return nil, err
+	}
+
+	params, err := getParamMap(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bsky.FeedGetFeed_Output
+	if err := c.do(ctx, xrpc.Query, "", "app.bsky.feed.getFeed", params, nil, &out); err != nil {
+		log.Err(err).Msg("Failed to call app.bsky.feed.getFeed.")
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,195 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rivo/uniseg"
+)
+
+// tagMaxGraphemes is the longest hashtag allowed by the lexicon.
+const tagMaxGraphemes = 64
+
+var (
+	// mentionRegexp matches @handles at the start of a word, the first group
+	// covering the mention and the second the handle.
+	mentionRegexp = regexp.MustCompile(`(?:^|[\s(])(@([a-zA-Z0-9.-]+[a-zA-Z0-9]))`)
+
+	// linkRegexp matches http(s) and www. links at the start of a word.
+	linkRegexp = regexp.MustCompile(`(?:^|[\s(])((?:https?://|www\.)\S+)`)
+
+	// tagRegexp matches #hashtags at the start of a word, the first group covering
+	// the hashtag and the second the tag without the #.
+	tagRegexp = regexp.MustCompile(`(?:^|\s)([#＃]([^\s#＃]+))`)
+)
+
+// RichText is post text annotated with facets: the mentions, links and hashtags
+// within it, located by UTF-8 byte offsets.
+type RichText struct {
+	Text   string
+	Facets []*bsky.RichtextFacet
+}
+
+// Graphemes returns the length of the text in user perceived characters, which
+// is what the post length limit is measured in.
+func (rt *RichText) Graphemes() int {
+	return uniseg.GraphemeClusterCount(rt.Text)
+}
+
+// Validate checks that the text fits in a post and that all facets lie within
+// it, returning a *ValidationError otherwise.
+func (rt *RichText) Validate() error {
+	verr := &ValidationError{Method: "com.atproto.repo.createRecord"}
+	if err := validatePost(rt.Post()); err != nil {
+		verr = err.(*ValidationError)
+	}
+	for i, facet := range rt.Facets {
+		if facet.Index == nil || facet.Index.ByteStart < 0 || facet.Index.ByteStart >= facet.Index.ByteEnd || facet.Index.ByteEnd > int64(len(rt.Text)) {
+			verr.Fields = append(verr.Fields, FieldError{Field: "Facets", Param: "facets", Value: facet, Reason: fmt.Sprintf("element %d must lie within the text", i)})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// Post returns the text and facets as a post to publish with CreatePost.
+func (rt *RichText) Post() *Post {
+	return &Post{Text: rt.Text, Facets: rt.Facets}
+}
+
+// FeedPost returns the text and facets as an app.bsky.feed.post record created
+// now, or a *ValidationError if the text does not fit in a post.
+func (rt *RichText) FeedPost() (*bsky.FeedPost, error) {
+	if err := rt.Validate(); err != nil {
+		return nil, err
+	}
+	return &bsky.FeedPost{
+		LexiconTypeID: postCollection,
+		Text:          rt.Text,
+		Facets:        rt.Facets,
+		CreatedAt:     time.Now().UTC().Format(syntax.AtprotoDatetimeLayout),
+	}, nil
+}
+
+func (c *client) DetectFacets(ctx context.Context, text string) (*RichText, error) {
+	rt := &RichText{Text: text}
+
+	// Mentions only become facets if the handle belongs to an account
+	dids := make(map[string]string)
+	for _, m := range mentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+		handle, err := syntax.ParseHandle(text[m[4]:m[5]])
+		if err != nil {
+			continue
+		}
+		handle = handle.Normalize()
+		did, ok := dids[handle.String()]
+		if !ok {
+			if did, err = c.resolveHandle(ctx, handle.String()); err != nil {
+				return nil, err
+			}
+			dids[handle.String()] = did
+		}
+		if did == "" {
+			continue
+		}
+		rt.Facets = append(rt.Facets, newFacet(m[2], m[3], &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Mention: &bsky.RichtextFacet_Mention{LexiconTypeID: "app.bsky.richtext.facet#mention", Did: did},
+		}))
+	}
+	rt.Facets = append(rt.Facets, detectLinks(text)...)
+	rt.Facets = append(rt.Facets, detectTags(text)...)
+
+	sort.Slice(rt.Facets, func(i, j int) bool {
+		return rt.Facets[i].Index.ByteStart < rt.Facets[j].Index.ByteStart
+	})
+	return rt, nil
+}
+
+// resolveHandle looks up the DID of a handle, returning an empty DID if there
+// is no account with the handle.
+func (c *client) resolveHandle(ctx context.Context, handle string) (string, error) {
+	var out atproto.IdentityResolveHandle_Output
+	err := c.do(ctx, xrpc.Query, "", "com.atproto.identity.resolveHandle", map[string]interface{}{"handle": handle}, nil, &out)
+	if err != nil {
+		var xerr *xrpc.Error
+		if errors.As(err, &xerr) && xerr.StatusCode == http.StatusBadRequest {
+			return "", nil
+		}
+		return "", err
+	}
+	return out.Did, nil
+}
+
+// detectLinks finds the http(s) and www. links in text. Trailing punctuation is
+// assumed to belong to the sentence rather than the link.
+func detectLinks(text string) []*bsky.RichtextFacet {
+	var facets []*bsky.RichtextFacet
+	for _, m := range linkRegexp.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2], m[3]
+		for end > start {
+			last := text[end-1]
+			if strings.IndexByte(".,;:!?\"'", last) >= 0 {
+				end--
+				continue
+			}
+			// Only keep closing parentheses that are part of the link itself
+			if last == ')' && strings.Count(text[start:end], "(") < strings.Count(text[start:end], ")") {
+				end--
+				continue
+			}
+			break
+		}
+		uri := text[start:end]
+		if strings.HasPrefix(uri, "www.") {
+			uri = "https://" + uri
+		}
+		if parsed, err := url.Parse(uri); err != nil || !strings.Contains(parsed.Host, ".") {
+			continue
+		}
+		facets = append(facets, newFacet(start, end, &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Link: &bsky.RichtextFacet_Link{LexiconTypeID: "app.bsky.richtext.facet#link", Uri: uri},
+		}))
+	}
+	return facets
+}
+
+// detectTags finds the hashtags in text. Trailing punctuation is not part of
+// the tag, and purely numeric tags like "#1" are skipped.
+func detectTags(text string) []*bsky.RichtextFacet {
+	var facets []*bsky.RichtextFacet
+	for _, m := range tagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		tag := strings.TrimRightFunc(text[m[4]:m[5]], unicode.IsPunct)
+		if tag == "" || strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		if uniseg.GraphemeClusterCount(tag) > tagMaxGraphemes {
+			continue
+		}
+		facets = append(facets, newFacet(m[2], m[4]+len(tag), &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Tag: &bsky.RichtextFacet_Tag{LexiconTypeID: "app.bsky.richtext.facet#tag", Tag: tag},
+		}))
+	}
+	return facets
+}
+
+// newFacet annotates the bytes [start, end) of a text with a feature.
+func newFacet(start int, end int, feature *bsky.RichtextFacet_Features_Elem) *bsky.RichtextFacet {
+	return &bsky.RichtextFacet{
+		Index:    &bsky.RichtextFacet_ByteSlice{ByteStart: int64(start), ByteEnd: int64(end)},
+		Features: []*bsky.RichtextFacet_Features_Elem{feature},
+	}
+}
@@ -0,0 +1,86 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a mock transport resolving the given handles to DIDs, and rejecting
+// any other handle as unknown.
+func newResolveHandleRoundTripper(dids map[string]string) *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/com.atproto.identity.resolveHandle"] = func(req *http.Request) *http.Response {
+		did, ok := dids[req.URL.Query().Get("handle")]
+		if !ok {
+			return &http.Response{
+				StatusCode: 400,
+				Body:       io.NopCloser(strings.NewReader(`{"error": "InvalidRequest", "message": "Unable to resolve handle"}`)),
+			}
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"did": "` + did + `"}`)),
+		}
+	}
+	return mockTransport
+}
+
+// Tests detecting facets, with byte offsets past multi-byte characters.
+func TestDetectFacets(t *testing.T) {
+	mockTransport := newResolveHandleRoundTripper(map[string]string{"alice.bsky.social": "did:plc:alice"})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	text := "👨‍👩‍👧 café́ @Alice.bsky.social and @nobody.bsky.social, see (https://example.com/a_(b)). #golang! #1 www.example.org/x?y=z #日本語"
+	rt, err := c.DetectFacets(context.Background(), text)
+	assert.NoError(t, err)
+
+	type facet struct {
+		Text    string
+		Feature string
+	}
+	var facets []facet
+	for _, f := range rt.Facets {
+		raw, _ := json.Marshal(f.Features[0])
+		facets = append(facets, facet{Text: text[f.Index.ByteStart:f.Index.ByteEnd], Feature: string(raw)})
+	}
+	assert.Equal(t, []facet{
+		{"@Alice.bsky.social", `{"$type":"app.bsky.richtext.facet#mention","did":"did:plc:alice"}`},
+		{"https://example.com/a_(b)", `{"$type":"app.bsky.richtext.facet#link","uri":"https://example.com/a_(b)"}`},
+		{"#golang", `{"$type":"app.bsky.richtext.facet#tag","tag":"golang"}`},
+		{"www.example.org/x?y=z", `{"$type":"app.bsky.richtext.facet#link","uri":"https://www.example.org/x?y=z"}`},
+		{"#日本語", `{"$type":"app.bsky.richtext.facet#tag","tag":"日本語"}`},
+	}, facets)
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.identity.resolveHandle"))
+	assert.NoError(t, rt.Validate())
+
+	post, err := rt.FeedPost()
+	assert.NoError(t, err)
+	assert.Equal(t, text, post.Text)
+	assert.Equal(t, rt.Facets, post.Facets)
+	assert.NotEmpty(t, post.CreatedAt)
+}
+
+// Tests that the length limit counts user perceived characters, not bytes or
+// code points.
+func TestRichTextGraphemes(t *testing.T) {
+	// An e with a combining accent is 2 code points and 3 bytes, but a single
+	// character
+	rt := &RichText{Text: strings.Repeat("e\u0301", postMaxGraphemes)}
+	assert.Equal(t, postMaxGraphemes, rt.Graphemes())
+	assert.NoError(t, rt.Validate())
+
+	rt.Text += "é"
+	assert.Equal(t, postMaxGraphemes+1, rt.Graphemes())
+	_, err := rt.FeedPost()
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	rt = &RichText{Text: "short", Facets: detectTags("short #tag")}
+	assert.ErrorIs(t, rt.Validate(), ErrInvalidRequest)
+}
@@ -0,0 +1,362 @@
+package bluesky
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// searchDateLayout is the date only format accepted by the since: and until:
+// search operators.
+const searchDateLayout = "2006-01-02"
+
+// SearchQuery composes a post search in the Bluesky search syntax, e.g.
+//
+//	q := NewSearchQuery().Phrase("grilled cheese").From("alice.bsky.social").Tag("food").Exclude("ham")
+//	q.String() // from:alice.bsky.social #food "grilled cheese" -ham
+//
+// Values are checked as they are added. Invalid ones are left out of the query
+// and reported by Err and Request as a *ValidationError.
+type SearchQuery struct {
+	terms    []string // Free text words and phrases
+	excluded []string // Words and phrases results must not contain
+	from     string   // Handle or DID of the author
+	mentions string   // Handle or DID of a mentioned account
+	tags     []string // Hashtags without the leading #
+	lang     string   // BCP-47 language of the posts
+	domain   string   // Domain the posts link to
+	since    time.Time
+	until    time.Time
+
+	errs []FieldError // Invalid values passed to the builder
+}
+
+// NewSearchQuery creates an empty search query.
+func NewSearchQuery() *SearchQuery {
+	return &SearchQuery{}
+}
+
+// Term adds words that posts must contain. Words the server would read as
+// operators, hashtags, mentions or phrases are quoted to keep them plain text.
+func (q *SearchQuery) Term(words ...string) *SearchQuery {
+	for _, word := range words {
+		for _, term := range strings.Fields(word) {
+			if isSearchSyntax(term) {
+				term = quoteSearchTerm(term)
+			}
+			q.terms = append(q.terms, term)
+		}
+	}
+	return q
+}
+
+// Phrase adds a sequence of words that posts must contain verbatim.
+func (q *SearchQuery) Phrase(phrase string) *SearchQuery {
+	if phrase = strings.TrimSpace(phrase); phrase != "" {
+		q.terms = append(q.terms, quoteSearchTerm(phrase))
+	}
+	return q
+}
+
+// Exclude adds a word or phrase that posts must not contain.
+func (q *SearchQuery) Exclude(term string) *SearchQuery {
+	if term = strings.TrimSpace(term); term != "" {
+		q.excluded = append(q.excluded, term)
+	}
+	return q
+}
+
+// From restricts the search to posts of an account, given by handle or DID.
+func (q *SearchQuery) From(actor string) *SearchQuery {
+	q.from = q.checkActor("Author", "from", actor)
+	return q
+}
+
+// Mentions restricts the search to posts mentioning an account, given by handle
+// or DID.
+func (q *SearchQuery) Mentions(actor string) *SearchQuery {
+	q.mentions = q.checkActor("Mentions", "mentions", actor)
+	return q
+}
+
+// Tag restricts the search to posts with all of the given hashtags. The leading
+// # is optional.
+func (q *SearchQuery) Tag(tags ...string) *SearchQuery {
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(tag, "#")
+		if tag == "" || strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+			q.errs = append(q.errs, FieldError{Field: "Tag", Param: "tag", Value: tag, Reason: "must be a single word"})
+			continue
+		}
+		q.tags = append(q.tags, tag)
+	}
+	return q
+}
+
+// Lang restricts the search to posts in a language, given as a BCP-47 tag.
+func (q *SearchQuery) Lang(lang string) *SearchQuery {
+	if _, err := syntax.ParseLanguage(lang); err != nil {
+		q.errs = append(q.errs, FieldError{Field: "Lang", Param: "lang", Value: lang, Reason: fmt.Sprintf("must be a valid language: %v", err)})
+		return q
+	}
+	q.lang = lang
+	return q
+}
+
+// Domain restricts the search to posts linking to a domain.
+func (q *SearchQuery) Domain(domain string) *SearchQuery {
+	if domain == "" || strings.ContainsAny(domain, " /:") {
+		q.errs = append(q.errs, FieldError{Field: "Domain", Param: "domain", Value: domain, Reason: "must be a hostname"})
+		return q
+	}
+	q.domain = domain
+	return q
+}
+
+// Since restricts the search to posts made at or after t.
+func (q *SearchQuery) Since(t time.Time) *SearchQuery {
+	q.since = t
+	return q
+}
+
+// Until restricts the search to posts made before t.
+func (q *SearchQuery) Until(t time.Time) *SearchQuery {
+	q.until = t
+	return q
+}
+
+// Err returns a *ValidationError listing every invalid value passed to the
+// builder, or nil if all of them were accepted.
+func (q *SearchQuery) Err() error {
+	if len(q.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Method: "app.bsky.feed.searchPosts", Fields: q.errs}
+}
+
+// String renders the whole query in the search syntax, suitable for the Q field
+// of a SearchPostsRequest or for showing it to the user.
+func (q *SearchQuery) String() string {
+	parts := q.operators()
+	for _, tag := range q.tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(append(parts, q.text()...), " ")
+}
+
+// Request converts the query into a search request, moving the operators into
+// their structured fields and leaving the remaining text in Q. If no text
+// remains, Q holds the rendered query since the server requires one.
+func (q *SearchQuery) Request() (*SearchPostsRequest, error) {
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	request := &SearchPostsRequest{
+		Q:        strings.Join(q.text(), " "),
+		Author:   q.from,
+		Mentions: q.mentions,
+		Tag:      q.tags,
+		Lang:     q.lang,
+		Domain:   q.domain,
+		Since:    q.since,
+		Until:    q.until,
+	}
+	if request.Q == "" {
+		request.Q = q.String()
+	}
+	return request, nil
+}
+
+// operators renders the filters of the query, except tags.
+func (q *SearchQuery) operators() []string {
+	var parts []string
+	if q.from != "" {
+		parts = append(parts, "from:"+q.from)
+	}
+	if q.mentions != "" {
+		parts = append(parts, "mentions:"+q.mentions)
+	}
+	if q.lang != "" {
+		parts = append(parts, "lang:"+q.lang)
+	}
+	if q.domain != "" {
+		parts = append(parts, "domain:"+q.domain)
+	}
+	if !q.since.IsZero() {
+		parts = append(parts, "since:"+formatSearchTime(q.since))
+	}
+	if !q.until.IsZero() {
+		parts = append(parts, "until:"+formatSearchTime(q.until))
+	}
+	return parts
+}
+
+// text renders the free text of the query: terms followed by exclusions.
+func (q *SearchQuery) text() []string {
+	parts := make([]string, 0, len(q.terms)+len(q.excluded))
+	parts = append(parts, q.terms...)
+	for _, term := range q.excluded {
+		if strings.ContainsAny(term, " \t\"") {
+			term = quoteSearchTerm(term)
+		}
+		parts = append(parts, "-"+term)
+	}
+	return parts
+}
+
+// checkActor validates the value of an account operator, returning it without
+// any leading @.
+func (q *SearchQuery) checkActor(field string, param string, actor string) string {
+	actor = strings.TrimPrefix(actor, "@")
+	if _, err := syntax.ParseAtIdentifier(actor); err != nil {
+		q.errs = append(q.errs, FieldError{Field: field, Param: param, Value: actor, Reason: fmt.Sprintf("must be a valid at-identifier: %v", err)})
+		return ""
+	}
+	return actor
+}
+
+// ParseSearchQuery parses a query typed in the search syntax into a builder,
+// e.g. to edit it in a UI. Unknown operators are kept as plain terms. Invalid
+// operator values are skipped and reported in the returned error, which is the
+// same as the query's Err.
+func ParseSearchQuery(s string) (*SearchQuery, error) {
+	q := NewSearchQuery()
+	for _, token := range tokenizeSearchQuery(s) {
+		if token.quoted {
+			if token.excluded {
+				q.Exclude(token.text)
+			} else {
+				q.Phrase(token.text)
+			}
+			continue
+		}
+		if token.excluded {
+			q.Exclude(token.text)
+			continue
+		}
+		if strings.HasPrefix(token.text, "#") && len(token.text) > 1 {
+			q.Tag(token.text)
+			continue
+		}
+		key, value, ok := strings.Cut(token.text, ":")
+		if !ok || !isSearchOperator(token.text) {
+			q.Term(token.text)
+			continue
+		}
+		switch key {
+		case "from":
+			q.From(value)
+		case "mentions":
+			q.Mentions(value)
+		case "lang":
+			q.Lang(value)
+		case "domain":
+			q.Domain(value)
+		case "since", "until":
+			t, err := parseSearchTime(value)
+			if err != nil {
+				q.errs = append(q.errs, FieldError{Field: map[string]string{"since": "Since", "until": "Until"}[key], Param: key, Value: value, Reason: "must be a date or datetime"})
+			} else if key == "since" {
+				q.Since(t)
+			} else {
+				q.Until(t)
+			}
+		}
+	}
+	return q, q.Err()
+}
+
+// searchToken is a word or quoted phrase of a search query.
+type searchToken struct {
+	text     string
+	quoted   bool // Whether the text was a quoted phrase
+	excluded bool // Whether the text was prefixed with -
+}
+
+// tokenizeSearchQuery splits a query at whitespace, keeping quoted phrases
+// together. Quotes inside phrases are escaped with a backslash, and an
+// unterminated phrase runs until the end of the query.
+func tokenizeSearchQuery(s string) []searchToken {
+	var (
+		tokens []searchToken
+		runes  = []rune(s)
+	)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		var token searchToken
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.excluded = true
+			i++
+		}
+		var text strings.Builder
+		if runes[i] == '"' {
+			token.quoted = true
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			i++ // Skip the closing quote
+		} else {
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				text.WriteRune(runes[i])
+			}
+		}
+		if token.text = text.String(); token.text != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// isSearchOperator reports whether a term would be interpreted as a filter.
+func isSearchOperator(term string) bool {
+	key, _, ok := strings.Cut(term, ":")
+	if !ok {
+		return false
+	}
+	switch key {
+	case "from", "mentions", "lang", "domain", "since", "until":
+		return true
+	}
+	return false
+}
+
+// isSearchSyntax reports whether a plain word would be interpreted as anything
+// but text: an operator, an exclusion, a hashtag, a mention or a phrase.
+func isSearchSyntax(term string) bool {
+	if isSearchOperator(term) || strings.Contains(term, `"`) {
+		return true
+	}
+	return len(term) > 1 && strings.ContainsAny(term[:1], "-#@")
+}
+
+// quoteSearchTerm renders text as a quoted phrase, escaping embedded quotes.
+func quoteSearchTerm(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// formatSearchTime renders a time for the since: and until: operators, as a
+// plain date if it falls on midnight UTC.
+func formatSearchTime(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(searchDateLayout)
+	}
+	return t.Format(syntax.AtprotoDatetimeLayout)
+}
+
+// parseSearchTime parses the value of the since: and until: operators.
+func parseSearchTime(value string) (time.Time, error) {
+	if t, err := time.Parse(searchDateLayout, value); err == nil {
+		return t, nil
+	}
+	return syntax.ParseDatetimeTime(value)
+}
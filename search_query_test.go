@@ -0,0 +1,108 @@
+package bluesky
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests rendering queries composed with the builder.
+func TestSearchQueryString(t *testing.T) {
+	since := time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 12, 24, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query *SearchQuery
+		want  string
+	}{
+		{"terms", NewSearchQuery().Term("grilled", "cheese  sandwich"), `grilled cheese sandwich`},
+		{"phrase", NewSearchQuery().Phrase(`say "cheese"`), `"say \"cheese\""`},
+		{"exclusions", NewSearchQuery().Term("cheese").Exclude("ham").Exclude("blue cheese"), `cheese -ham -"blue cheese"`},
+		{"operator like terms", NewSearchQuery().Term("from:alice.bsky.social", "-1"), `"from:alice.bsky.social" "-1"`},
+		{"syntax like terms", NewSearchQuery().Term(`say"hi`, "#food", "@alice", "#", "@"), `"say\"hi" "#food" "@alice" # @`},
+		{"actors", NewSearchQuery().From("@alice.bsky.social").Mentions("did:plc:test"), `from:alice.bsky.social mentions:did:plc:test`},
+		{"tags", NewSearchQuery().Tag("#go", "bluesky"), `#go #bluesky`},
+		{"filters", NewSearchQuery().Lang("pt-BR").Domain("example.com").Since(since).Until(until), `lang:pt-BR domain:example.com since:2024-11-05 until:2024-12-24T18:30:00Z`},
+		{"everything", NewSearchQuery().Phrase("grilled cheese").From("alice.bsky.social").Tag("food").Exclude("ham"), `from:alice.bsky.social #food "grilled cheese" -ham`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.NoError(t, test.query.Err())
+			assert.Equal(t, test.want, test.query.String())
+		})
+	}
+}
+
+// Tests that invalid values are left out and all of them reported.
+func TestSearchQueryValidation(t *testing.T) {
+	q := NewSearchQuery().Term("cheese").From("not a handle").Mentions("alice.bsky.social").Tag("two words").Lang("??").Domain("https://example.com")
+	assert.Equal(t, "mentions:alice.bsky.social cheese", q.String())
+
+	_, err := q.Request()
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		var params []string
+		for _, field := range verr.Fields {
+			params = append(params, field.Param)
+		}
+		assert.Equal(t, []string{"from", "tag", "lang", "domain"}, params)
+	}
+}
+
+// Tests splitting a query into the structured fields of a request.
+func TestSearchQueryRequest(t *testing.T) {
+	since := time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC)
+
+	request, err := NewSearchQuery().Phrase("grilled cheese").Exclude("ham").From("alice.bsky.social").Tag("food").Lang("en").Since(since).Request()
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchPostsRequest{
+		Q:      `"grilled cheese" -ham`,
+		Author: "alice.bsky.social",
+		Tag:    []string{"food"},
+		Lang:   "en",
+		Since:  since,
+	}, request)
+
+	// The server requires some query text even if everything is a filter
+	request, err = NewSearchQuery().From("alice.bsky.social").Request()
+	assert.NoError(t, err)
+	assert.Equal(t, "from:alice.bsky.social", request.Q)
+	assert.Equal(t, "alice.bsky.social", request.Author)
+}
+
+// Tests parsing user typed queries back into the builder.
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`cheese`, `cheese`},
+		{`  grilled   cheese `, `grilled cheese`},
+		{`"grilled cheese" -ham -"blue cheese"`, `"grilled cheese" -ham -"blue cheese"`},
+		{`"say \"cheese\""`, `"say \"cheese\""`},
+		{`"unterminated phrase`, `"unterminated phrase"`},
+		{`cheese from:@alice.bsky.social #food mentions:did:plc:test`, `from:alice.bsky.social mentions:did:plc:test #food cheese`},
+		{`lang:en domain:example.com since:2024-11-05 until:2024-12-24T18:30:00Z`, `lang:en domain:example.com since:2024-11-05 until:2024-12-24T18:30:00Z`},
+		{`https://example.com to:alice - # -from:bob`, `https://example.com to:alice - # -from:bob`},
+		{`say"hi @alice`, `"say\"hi" "@alice"`},
+		{`"#food" cheese`, `"#food" cheese`},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := ParseSearchQuery(test.query)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, q.String())
+
+			// Rendered queries parse back into themselves
+			again, err := ParseSearchQuery(q.String())
+			assert.NoError(t, err)
+			assert.Equal(t, q.String(), again.String())
+		})
+	}
+
+	q, err := ParseSearchQuery(`cheese from:not@valid since:yesterday`)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, "cheese", q.String())
+}
@@ -0,0 +1,200 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrSessionNotFound is returned from SessionStore.Load if there is no session
+// persisted in the store yet.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore persists the authenticated session of a client, so that it can
+// be resumed after a restart instead of logging in again, which is heavily rate
+// limited by the Bluesky servers.
+type SessionStore interface {
+	// Load retrieves the persisted session, or ErrSessionNotFound if none was
+	// saved yet.
+	Load(ctx context.Context) (*xrpc.AuthInfo, error)
+
+	// Save persists the session, replacing any previously saved one.
+	Save(ctx context.Context, auth *xrpc.AuthInfo) error
+}
+
+// NewClientFromSession creates a new client authenticated to the Bluesky server,
+// resuming the session persisted in the store if there is one. The session is
+// refreshed if it is about to expire, and a new login is done with the given
+// handle and appkey only if there is no usable session to resume. The client
+// saves its session into the store whenever it changes.
+func NewClientFromSession(ctx context.Context, server string, handle string, appkey string, store SessionStore, clientOptions ...clientOption) (Client, error) {
+	params := newClientOptionalParams(server, handle, appkey, clientOptions)
+	params.store = store
+
+	return newClientFromSessionInternal(ctx, handle, appkey, params)
+}
+
+func newClientFromSessionInternal(ctx context.Context, handle string, appkey string, params *clientOptionalParams) (Client, error) {
+	auth, err := params.store.Load(ctx)
+	if errors.Is(err, ErrSessionNotFound) {
+		log.Info().Msg("No persisted session found, logging in.")
+		return newClientInternal(ctx, handle, appkey, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if auth.Handle != handle && auth.Did != handle {
+		log.Info().Msgf("Persisted session belongs to %v, logging in as %v.", auth.Handle, handle)
+		return newClientInternal(ctx, handle, appkey, params)
+	}
+	c, err := newClientFromAuth(auth, params)
+	if err != nil {
+		log.Err(err).Msg("Persisted session unusable, logging in.")
+		return newClientInternal(ctx, handle, appkey, params)
+	}
+	if err := c.resumeSession(ctx); err != nil {
+		c.shutdown()
+		if !errors.Is(err, ErrSessionExpired) {
+			return nil, err
+		}
+		log.Info().Msg("Persisted session expired, logging in.")
+		return newClientInternal(ctx, handle, appkey, params)
+	}
+	c.start(params.refreshRetryPause)
+
+	return c, nil
+}
+
+// resumeSession ensures that a resumed session is still valid for use, doing a
+// synchronous refresh if the access token is about to expire. It returns an
+// error wrapping ErrSessionExpired if the session cannot be refreshed anymore.
+func (c *client) resumeSession(ctx context.Context) error {
+	var (
+		sess = c.session.Load()
+		now  = c.clock.Now()
+	)
+	if sess.refreshJwtExpire.Before(now) {
+		return fmt.Errorf("%w: refresh token was valid until %v", ErrSessionExpired, sess.refreshJwtExpire)
+	}
+	if sess.accessJwtExpire.Sub(now) >= c.syncRefreshThreshold {
+		return nil
+	}
+	err := c.refreshJWT(ctx)
+	if isTokenExpired(err) {
+		return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+	return err
+}
+
+// CredentialProvider returns the handle and app key to log in with whenever the
+// client needs to create a new session.
+type CredentialProvider func(ctx context.Context) (handle string, appkey string, err error)
+
+// WithReauthentication makes the client log in again once its session expires
+// and cannot be refreshed anymore, instead of becoming unusable. The credentials
+// for the new login are retrieved from the provider, or if it is nil, the ones
+// the client was created with are reused. Use SessionHooks.OnRelogin to audit
+// the new logins.
+func WithReauthentication(provider CredentialProvider) clientOption {
+	return func(params *clientOptionalParams) {
+		params.reauthenticate = true
+		params.credentials = provider
+	}
+}
+
+// MemorySessionStore is a SessionStore keeping the session in memory. It is
+// useful to share a session between clients within the same process.
+type MemorySessionStore struct {
+	lock sync.Mutex
+	auth *xrpc.AuthInfo
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{}
+}
+
+func (s *MemorySessionStore) Load(ctx context.Context) (*xrpc.AuthInfo, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.auth == nil {
+		return nil, ErrSessionNotFound
+	}
+	auth := *s.auth
+	return &auth, nil
+}
+
+func (s *MemorySessionStore) Save(ctx context.Context, auth *xrpc.AuthInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	saved := *auth
+	s.auth = &saved
+	return nil
+}
+
+// FileSessionStore is a SessionStore keeping the session in a JSON file. The file
+// holds live credentials, so it is only readable by its owner.
+type FileSessionStore struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileSessionStore creates a session store backed by the file at path. The
+// file is created on the first save.
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{path: path}
+}
+
+func (s *FileSessionStore) Load(ctx context.Context) (*xrpc.AuthInfo, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	auth := new(xrpc.AuthInfo)
+	if err := json.Unmarshal(blob, auth); err != nil {
+		return nil, fmt.Errorf("error parsing session file %v: %v", s.path, err)
+	}
+	return auth, nil
+}
+
+func (s *FileSessionStore) Save(ctx context.Context, auth *xrpc.AuthInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, err := json.Marshal(auth)
+	if err != nil {
+		return err
+	}
+	// Write into a temporary file and move it over the old one, so that a crash
+	// mid-write doesn't leave a corrupt session behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,183 @@
+package bluesky
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that sessions survive a round trip through the file store, and that the
+// file is not readable by others.
+func TestFileSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	store := NewFileSessionStore(path)
+
+	_, err := store.Load(context.Background())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	auth := &xrpc.AuthInfo{
+		AccessJwt:  "access",
+		RefreshJwt: "refresh",
+		Handle:     "test.bsky.social",
+		Did:        "did:plc:test",
+	}
+	assert.NoError(t, store.Save(context.Background(), auth))
+
+	loaded, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, auth, loaded)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+// Returns a mock transport with basic session handling logic in place, which
+// answers session refreshes with the given response.
+func newResumeMockRoundTripper(refreshResponse *http.Response) *mockRoundTripper {
+	mockTransport := newDefaultMockRoundTripper()
+	if refreshResponse != nil {
+		mockTransport.responseMap["/xrpc/com.atproto.server.refreshSession"] = refreshResponse
+	}
+	return mockTransport
+}
+
+func newResumedClient(t *testing.T, transport http.RoundTripper, store SessionStore, clock clockInterface) Client {
+	c, err := NewClientFromSession(context.Background(), ServerBskySocial, "test.bsky.social", "testAppkey", store,
+		withClock(clock),
+		withXrpcClient(&xrpc.Client{
+			Client: &http.Client{
+				Transport: transport,
+			},
+			Host: ServerBskySocial,
+		}))
+	if err != nil {
+		t.Fatalf("failed to resume client: %v", err)
+	}
+	return c
+}
+
+// Tests that a valid persisted session is resumed without logging in.
+func TestResumeSession(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	store := NewMemorySessionStore()
+	store.Save(context.Background(), &xrpc.AuthInfo{
+		AccessJwt:  getAccessJwt(now, now.Add(time.Hour)),
+		RefreshJwt: getRefreshJwt(now, now.Add(72*time.Hour)),
+		Handle:     "test.bsky.social",
+		Did:        "did:plc:test",
+	})
+	mockTransport := newResumeMockRoundTripper(nil)
+
+	c := newResumedClient(t, mockTransport, store, clock)
+	defer c.Close()
+
+	assert.True(t, c.Ready())
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+}
+
+// Tests that a persisted session about to expire is refreshed on resume, and the
+// refreshed session is persisted.
+func TestResumeSessionRefresh(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	store := NewMemorySessionStore()
+	store.Save(context.Background(), &xrpc.AuthInfo{
+		AccessJwt:  getAccessJwt(now, now.Add(-time.Minute)),
+		RefreshJwt: getRefreshJwt(now, now.Add(72*time.Hour)),
+		Handle:     "test.bsky.social",
+		Did:        "did:plc:test",
+	})
+	refreshedAccessJwt := getAccessJwt(now, now.Add(2*time.Hour))
+	mockTransport := newResumeMockRoundTripper(&http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(getRefreshSessionResponse(refreshedAccessJwt, getRefreshJwt(now, now.Add(96*time.Hour))))),
+	})
+
+	c := newResumedClient(t, mockTransport, store, clock)
+	defer c.Close()
+
+	assert.True(t, c.Ready())
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+
+	auth, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, refreshedAccessJwt, auth.AccessJwt)
+}
+
+// Tests that a new login is done if the persisted session cannot be refreshed.
+func TestResumeSessionRejected(t *testing.T) {
+	clock := &mockClock{time: time.Now()}
+	now := clock.Now()
+
+	store := NewMemorySessionStore()
+	store.Save(context.Background(), &xrpc.AuthInfo{
+		AccessJwt:  getAccessJwt(now, now.Add(-time.Minute)),
+		RefreshJwt: getRefreshJwt(now, now.Add(72*time.Hour)),
+		Handle:     "test.bsky.social",
+		Did:        "did:plc:test",
+	})
+	mockTransport := newResumeMockRoundTripper(&http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader(`{"error": "ExpiredToken", "message": "Token has been revoked"}`)),
+	})
+
+	c := newResumedClient(t, mockTransport, store, clock)
+	defer c.Close()
+
+	assert.True(t, c.Ready())
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.refreshSession"))
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+
+	// The fresh session replaced the dead one
+	auth, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, getAccessJwt(now, now.Add(-time.Minute)), auth.AccessJwt)
+}
+
+// Tests that a new login is done if the persisted session is corrupt, e.g. its
+// tokens were truncated.
+func TestResumeSessionCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"accessJwt": "", "refreshJwt": "header.payload", "handle": "test.bsky.social", "did": "did:plc:test"}`), 0600))
+	store := NewFileSessionStore(path)
+	mockTransport := newDefaultMockRoundTripper()
+
+	c := newResumedClient(t, mockTransport, store, &realClockImpl{})
+	defer c.Close()
+
+	assert.True(t, c.Ready())
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+
+	auth, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, auth.AccessJwt)
+}
+
+// Tests that a new login is done and persisted if the store is empty.
+func TestResumeSessionNotFound(t *testing.T) {
+	store := NewMemorySessionStore()
+	mockTransport := newDefaultMockRoundTripper()
+
+	c := newResumedClient(t, mockTransport, store, &realClockImpl{})
+	defer c.Close()
+
+	assert.Equal(t, 1, mockTransport.calls("/xrpc/com.atproto.server.createSession"))
+
+	auth, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "did:plc:test", auth.Did)
+}
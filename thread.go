@@ -0,0 +1,223 @@
+package bluesky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/rivo/uniseg"
+	"github.com/rs/zerolog/log"
+)
+
+// threadBreak ranks the places text may be split at, from worst to best.
+type threadBreak int
+
+const (
+	threadBreakWord      threadBreak = iota + 1 // Before whitespace
+	threadBreakSentence                         // After sentence ending punctuation
+	threadBreakParagraph                        // Before a line break
+)
+
+// ThreadOptions configure how PostThread splits and posts text.
+type ThreadOptions struct {
+	Numbered bool     // Whether to end each part with a counter like "1/4"
+	Langs    []string // BCP-47 languages of the text, at most 3
+}
+
+func (c *client) PostThread(ctx context.Context, rt *RichText, opts *ThreadOptions) ([]*atproto.RepoStrongRef, error) {
+	if opts == nil {
+		opts = &ThreadOptions{}
+	}
+	if rt == nil {
+		rt = &RichText{}
+	}
+	parts, err := rt.Split(opts.Numbered)
+	if err != nil {
+		return nil, err
+	}
+	// Check all parts before posting any of them
+	posts := make([]*Post, len(parts))
+	for i, part := range parts {
+		posts[i] = &Post{Text: part.Text, Facets: part.Facets, Langs: opts.Langs}
+		if err := validatePost(posts[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(posts) == 0 {
+		return nil, validatePost(&Post{})
+	}
+
+	refs := make([]*atproto.RepoStrongRef, 0, len(posts))
+	for i, post := range posts {
+		var reply *bsky.FeedPost_ReplyRef
+		if i > 0 {
+			reply = &bsky.FeedPost_ReplyRef{Root: refs[0], Parent: refs[i-1]}
+		}
+		ref, err := c.createPost(ctx, post, reply)
+		if err != nil {
+			return c.rollbackThread(ctx, refs, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// rollbackThread deletes the posted parts of a failed thread, newest first. It
+// returns the parts it failed to delete alongside the error that caused the
+// rollback.
+func (c *client) rollbackThread(ctx context.Context, refs []*atproto.RepoStrongRef, cause error) ([]*atproto.RepoStrongRef, error) {
+	// Clean up even if the thread failed because ctx was cancelled
+	ctx = context.WithoutCancel(ctx)
+	for len(refs) > 0 {
+		ref := refs[len(refs)-1]
+		if err := c.DeletePost(ctx, ref.Uri); err != nil {
+			log.Err(err).Msg("Failed to roll back thread.")
+			return refs, errors.Join(cause, fmt.Errorf("rolling back thread: %w", err))
+		}
+		refs = refs[:len(refs)-1]
+	}
+	return nil, cause
+}
+
+// Split breaks the text into parts that each fit in a post, cutting at line,
+// sentence or word boundaries where possible but never inside a facet. If
+// numbered and there are multiple parts, each part ends with a counter like
+// "1/4". Leading and trailing whitespace of the parts is dropped.
+func (rt *RichText) Split(numbered bool) ([]*RichText, error) {
+	parts, err := rt.split(0)
+	if err != nil || !numbered || len(parts) < 2 {
+		return parts, err
+	}
+	// Counters take room away from the text, which may need more parts and thus
+	// longer counters in turn
+	for reserve := 0; len(threadCounter(len(parts), len(parts))) > reserve; {
+		reserve = len(threadCounter(len(parts), len(parts)))
+		if parts, err = rt.split(reserve); err != nil {
+			return nil, err
+		}
+	}
+	for i, part := range parts {
+		part.Text += threadCounter(i+1, len(parts))
+	}
+	return parts, nil
+}
+
+// split breaks the text into parts, leaving reserve bytes and graphemes of room
+// in each of them.
+func (rt *RichText) split(reserve int) ([]*RichText, error) {
+	var (
+		parts []*RichText
+		text  = strings.TrimRightFunc(rt.Text, unicode.IsSpace)
+		start = skipSpace(text, 0)
+	)
+	for start < len(text) {
+		end := fitGraphemes(text, start, postMaxGraphemes-reserve, postMaxTextBytes-reserve)
+		if end < len(text) {
+			if end = rt.breakBefore(text, start, end); end == start {
+				return nil, &ValidationError{Method: "com.atproto.repo.createRecord", Fields: []FieldError{{Field: "Facets", Param: "facets", Value: text[start:], Reason: "must each fit in a single post"}}}
+			}
+		}
+		parts = append(parts, rt.slice(start, start+len(strings.TrimRightFunc(text[start:end], unicode.IsSpace))))
+		start = skipSpace(text, end)
+	}
+	return parts, nil
+}
+
+// breakBefore finds the best place to split text[start:] at or before limit.
+// Breaks in the first half are only used if there is no word break at all,
+// so that parts don't end up needlessly short.
+func (rt *RichText) breakBefore(text string, start int, limit int) int {
+	best := make(map[threadBreak]int)
+	for i, r := range text[start:] {
+		pos := start + i
+		if pos > limit {
+			break
+		}
+		if pos == start || !unicode.IsSpace(r) || rt.insideFacet(pos) {
+			continue
+		}
+		best[threadBreakWord] = pos
+		if r == '\n' {
+			best[threadBreakParagraph] = pos
+		}
+		if endsSentence(text[start:pos]) {
+			best[threadBreakSentence] = pos
+		}
+	}
+	for kind := threadBreakParagraph; kind >= threadBreakWord; kind-- {
+		if pos, ok := best[kind]; ok && (pos-start >= (limit-start)/2 || kind == threadBreakWord) {
+			return pos
+		}
+	}
+	// No whitespace to break at, cut the word but keep facets whole
+	for _, facet := range rt.Facets {
+		if facet.Index != nil && int(facet.Index.ByteStart) < limit && int(facet.Index.ByteEnd) > limit {
+			return max(start, int(facet.Index.ByteStart))
+		}
+	}
+	return limit
+}
+
+// insideFacet reports whether splitting the text at pos would cut a facet.
+func (rt *RichText) insideFacet(pos int) bool {
+	for _, facet := range rt.Facets {
+		if facet.Index != nil && int(facet.Index.ByteStart) < pos && int(facet.Index.ByteEnd) > pos {
+			return true
+		}
+	}
+	return false
+}
+
+// slice returns text[start:end] along with the facets lying within it.
+func (rt *RichText) slice(start int, end int) *RichText {
+	part := &RichText{Text: rt.Text[start:end]}
+	for _, facet := range rt.Facets {
+		if facet.Index == nil || int(facet.Index.ByteStart) < start || int(facet.Index.ByteEnd) > end {
+			continue
+		}
+		part.Facets = append(part.Facets, &bsky.RichtextFacet{
+			Index: &bsky.RichtextFacet_ByteSlice{
+				ByteStart: facet.Index.ByteStart - int64(start),
+				ByteEnd:   facet.Index.ByteEnd - int64(start),
+			},
+			Features: facet.Features,
+		})
+	}
+	return part
+}
+
+// fitGraphemes returns the end of the longest run of whole graphemes from start
+// that stays within both limits.
+func fitGraphemes(text string, start int, maxGraphemes int, maxBytes int) int {
+	end, state := start, -1
+	for n := 0; n < maxGraphemes && end < len(text); n++ {
+		cluster, _, _, next := uniseg.FirstGraphemeClusterInString(text[end:], state)
+		if end+len(cluster)-start > maxBytes {
+			break
+		}
+		end, state = end+len(cluster), next
+	}
+	return end
+}
+
+// skipSpace returns the position of the first non whitespace at or after pos.
+func skipSpace(text string, pos int) int {
+	return len(text) - len(strings.TrimLeftFunc(text[pos:], unicode.IsSpace))
+}
+
+// endsSentence reports whether text ends with sentence ending punctuation,
+// possibly followed by closing quotes or brackets.
+func endsSentence(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimRight(text, `"')]”’`))
+	return strings.ContainsRune(".!?…。！？", r)
+}
+
+// threadCounter renders the counter ending the i-th of n parts.
+func threadCounter(i int, n int) string {
+	return fmt.Sprintf(" %d/%d", i, n)
+}
@@ -0,0 +1,199 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/api/bsky"
+	"github.com/rivo/uniseg"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that text is split at the best boundary within the limits.
+func TestRichTextSplit(t *testing.T) {
+	sentence := strings.Repeat("word ", 30) + "end. " // 155 bytes
+	tests := []struct {
+		name  string
+		text  string
+		parts []string
+	}{
+		{"fits", "  short post  ", []string{"short post"}},
+		{"sentences", sentence + sentence + sentence, []string{
+			strings.TrimSpace(sentence),
+			strings.TrimSpace(sentence),
+			strings.TrimSpace(sentence),
+		}},
+		{"paragraphs", "intro\n\n" + strings.Repeat("a", 200) + "\n" + strings.Repeat("b", 200), []string{
+			"intro\n\n" + strings.Repeat("a", 200),
+			strings.Repeat("b", 200),
+		}},
+		{"words", strings.Repeat("abcdefghi ", 40), []string{
+			strings.TrimSpace(strings.Repeat("abcdefghi ", 30)),
+			strings.TrimSpace(strings.Repeat("abcdefghi ", 10)),
+		}},
+		{"no whitespace", strings.Repeat("x", 350), []string{
+			strings.Repeat("x", 300),
+			strings.Repeat("x", 50),
+		}},
+		{"graphemes", strings.Repeat("é", 301), []string{
+			strings.Repeat("é", 300),
+			"é",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parts, err := (&RichText{Text: test.text}).Split(false)
+			assert.NoError(t, err)
+			texts := make([]string, len(parts))
+			for i, part := range parts {
+				texts[i] = part.Text
+			}
+			assert.Equal(t, test.parts, texts)
+		})
+	}
+}
+
+// Tests that numbered parts make room for their counters.
+func TestRichTextSplitNumbered(t *testing.T) {
+	parts, err := (&RichText{Text: strings.Repeat("x", 2990)}).Split(true)
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 11) {
+		assert.True(t, strings.HasSuffix(parts[0].Text, "x 1/11"))
+		assert.True(t, strings.HasSuffix(parts[10].Text, "x 11/11"))
+	}
+	for _, part := range parts {
+		assert.LessOrEqual(t, uniseg.GraphemeClusterCount(part.Text), postMaxGraphemes)
+	}
+
+	// A single part is left unnumbered
+	parts, err = (&RichText{Text: "short post"}).Split(true)
+	assert.NoError(t, err)
+	assert.Equal(t, "short post", parts[0].Text)
+}
+
+// Tests that facets are never cut and follow their text into the parts.
+func TestRichTextSplitFacets(t *testing.T) {
+	link := "https://example.com/" + strings.Repeat("long-path/", 5)
+	text := strings.Repeat("x", 280) + " " + link + " done"
+	start := int64(strings.Index(text, link))
+	rt := &RichText{Text: text, Facets: []*bsky.RichtextFacet{
+		newFacet(int(start), int(start)+len(link), &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Link: &bsky.RichtextFacet_Link{LexiconTypeID: "app.bsky.richtext.facet#link", Uri: link},
+		}),
+	}}
+	parts, err := rt.Split(false)
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, strings.Repeat("x", 280), parts[0].Text)
+		assert.Empty(t, parts[0].Facets)
+		assert.Equal(t, link+" done", parts[1].Text)
+		if assert.Len(t, parts[1].Facets, 1) {
+			assert.Equal(t, int64(0), parts[1].Facets[0].Index.ByteStart)
+			assert.Equal(t, int64(len(link)), parts[1].Facets[0].Index.ByteEnd)
+		}
+	}
+
+	// Facets spanning whitespace are kept whole too
+	text = strings.Repeat("y", 290) + " two words and more"
+	rt = &RichText{Text: text, Facets: []*bsky.RichtextFacet{
+		newFacet(291, 304, &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Link: &bsky.RichtextFacet_Link{LexiconTypeID: "app.bsky.richtext.facet#link", Uri: "https://example.com"},
+		}),
+	}}
+	parts, err = rt.Split(false)
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, strings.Repeat("y", 290), parts[0].Text)
+		assert.Equal(t, "two words and more", parts[1].Text)
+	}
+
+	// Facets too long for a post cannot be kept whole
+	rt = &RichText{Text: strings.Repeat("z", 400), Facets: []*bsky.RichtextFacet{
+		newFacet(0, 400, &bsky.RichtextFacet_Features_Elem{
+			RichtextFacet_Link: &bsky.RichtextFacet_Link{LexiconTypeID: "app.bsky.richtext.facet#link", Uri: "https://example.com"},
+		}),
+	}}
+	_, err = rt.Split(false)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// Tests that each part of a thread replies to the previous one, all sharing
+// the first part as root.
+func TestPostThread(t *testing.T) {
+	records := make(map[string]string)
+	c := newMockClient(t, newRepoRoundTripper(records))
+	defer c.Close()
+
+	text := strings.Repeat("This is a sentence. ", 40)
+	refs, err := c.PostThread(context.Background(), &RichText{Text: text}, &ThreadOptions{Numbered: true, Langs: []string{"en"}})
+	assert.NoError(t, err)
+	if !assert.Len(t, refs, 3) {
+		return
+	}
+	for i, ref := range refs {
+		var record bsky.FeedPost
+		assert.NoError(t, json.Unmarshal([]byte(records[ref.Uri]), &record))
+		assert.Equal(t, []string{"en"}, record.Langs)
+		assert.True(t, strings.HasSuffix(record.Text, fmt.Sprintf("sentence. %d/3", i+1)))
+		if i == 0 {
+			assert.Nil(t, record.Reply)
+			continue
+		}
+		assert.Equal(t, refs[0].Uri, record.Reply.Root.Uri)
+		assert.Equal(t, refs[i-1].Uri, record.Reply.Parent.Uri)
+		assert.Equal(t, refs[i-1].Cid, record.Reply.Parent.Cid)
+	}
+}
+
+// Tests that the posted parts of a failed thread are deleted again.
+func TestPostThreadRollback(t *testing.T) {
+	records := make(map[string]string)
+	mockTransport := newRepoRoundTripper(records)
+	createRecord := mockTransport.responseFuncs["/xrpc/com.atproto.repo.createRecord"]
+	mockTransport.responseFuncs["/xrpc/com.atproto.repo.createRecord"] = func(req *http.Request) *http.Response {
+		if len(records) == 2 {
+			return &http.Response{
+				StatusCode: 400,
+				Body:       io.NopCloser(strings.NewReader(`{"error": "InvalidRequest"}`)),
+			}
+		}
+		return createRecord(req)
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	refs, err := c.PostThread(context.Background(), &RichText{Text: strings.Repeat("word ", 200)}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, refs)
+	assert.Empty(t, records)
+	assert.Equal(t, 3, mockTransport.calls("/xrpc/com.atproto.repo.createRecord"))
+	assert.Equal(t, 2, mockTransport.calls("/xrpc/com.atproto.repo.deleteRecord"))
+
+	// Nothing is posted without any text
+	_, err = c.PostThread(context.Background(), &RichText{Text: "   "}, nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 3, mockTransport.calls("/xrpc/com.atproto.repo.createRecord"))
+}
+
+// Tests that threads without text are rejected before posting anything.
+func TestPostThreadEmpty(t *testing.T) {
+	mockTransport := newRepoRoundTripper(make(map[string]string))
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	for _, rt := range []*RichText{nil, {}, {Text: " \n "}} {
+		refs, err := c.PostThread(context.Background(), rt, nil)
+		assert.Nil(t, refs)
+		var verr *ValidationError
+		if assert.ErrorAs(t, err, &verr) {
+			assert.Equal(t, "com.atproto.repo.createRecord", verr.Method)
+			assert.Equal(t, "text", verr.Fields[0].Param)
+		}
+	}
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/com.atproto.repo.createRecord"))
+}
@@ -39,6 +39,10 @@ type mockRoundTripper struct {
 	// map path -> response
 	responseMap map[string]*http.Response
 
+	// map path -> response generator, takes precedence over responseMap. Useful
+	// when the response depends on the request or the path is called many times.
+	responseFuncs map[string]func(req *http.Request) *http.Response
+
 	// tracks the number of times each RPC method had been called.
 	calledMethods map[string]int
 
@@ -46,7 +50,11 @@ type mockRoundTripper struct {
 }
 
 func newMockRoundTripper(responseMap map[string]*http.Response) *mockRoundTripper {
-	return &mockRoundTripper{responseMap: responseMap, calledMethods: make(map[string]int)}
+	return &mockRoundTripper{
+		responseMap:   responseMap,
+		responseFuncs: make(map[string]func(req *http.Request) *http.Response),
+		calledMethods: make(map[string]int),
+	}
 }
 
 // Returns a mockRoundTripper with basic session handling logic in place.
@@ -65,12 +73,24 @@ func newDefaultMockRoundTripper() *mockRoundTripper {
 	return newMockRoundTripper(responses)
 }
 
+// calls returns the number of times the given path was requested.
+func (m *mockRoundTripper) calls(path string) int {
+	m.calledMethodsMutex.Lock()
+	defer m.calledMethodsMutex.Unlock()
+
+	return m.calledMethods[path]
+}
+
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	m.calledMethodsMutex.Lock()
 	defer m.calledMethodsMutex.Unlock()
 	fmt.Printf("called method: %v\n", req.URL.Path)
 	m.calledMethods[req.URL.Path] += 1
 
+	if responseFunc, found := m.responseFuncs[req.URL.Path]; found {
+		return responseFunc(req), nil
+	}
+
 	response, found := m.responseMap[req.URL.Path]
 
 	if !found {
@@ -82,3 +102,23 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 
 	return response, nil
 }
+
+// hangingRoundTripper wraps a mockRoundTripper, but never answers requests to
+// the given path. Those only return once the request's context is done, which
+// is used to test cancellation and deadline handling.
+type hangingRoundTripper struct {
+	*mockRoundTripper
+	path string
+}
+
+func (h *hangingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != h.path {
+		return h.mockRoundTripper.RoundTrip(req)
+	}
+	h.calledMethodsMutex.Lock()
+	h.calledMethods[req.URL.Path] += 1
+	h.calledMethodsMutex.Unlock()
+
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
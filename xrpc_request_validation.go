@@ -2,11 +2,30 @@ package bluesky
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
 )
 
+// timeType is the reflected type of time.Time, which is encoded as a value
+// rather than as a struct.
+var timeType = reflect.TypeOf(time.Time{})
+
 // reflect all fields from request into a map. Used for getting param maps to send in xRPC requests.
+//
+// Fields are encoded according to their `xrpc` struct tag, which holds the
+// parameter name followed by comma separated options, e.g.
+// `xrpc:"since,datetime,omitempty"`. The omitempty option skips the parameter
+// if the field holds its zero value, and datetime formats a time.Time as an AT
+// Protocol datetime (RFC 3339 in UTC). Fields without a tag use their name in
+// camelCase, and fields tagged "-" are skipped. Slices are sent as repeated
+// parameters, one per element.
 func getParamMap(request any) (map[string]interface{}, error) {
 	params := make(map[string]interface{})
 
@@ -29,12 +48,298 @@ func getParamMap(request any) (map[string]interface{}, error) {
 	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
-		fieldName := strings.ToLower(t.Field(i).Name)
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		name, opts := parseXrpcTag(structField)
+		if name == "-" {
+			continue
+		}
+		if opts.omitempty && field.IsZero() {
+			continue
+		}
 
-		if !field.IsZero() {
-			params[fieldName] = field.Interface()
+		value, err := encodeParam(field, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", structField.Name, err)
+		}
+		if value != nil {
+			params[name] = value
 		}
 	}
 
 	return params, nil
 }
+
+// xrpcTagOptions are the options following the name in an `xrpc` struct tag.
+// Besides the encoding options, they hold the lexicon constraints checked by
+// validateRequest.
+type xrpcTagOptions struct {
+	omitempty bool
+	datetime  bool
+
+	required  bool     // The parameter must be set
+	format    string   // Lexicon string format, e.g. "at-identifier"
+	enum      []string // Allowed values, from "enum=a|b"
+	minimum   *int64   // Smallest allowed integer
+	maximum   *int64   // Largest allowed integer
+	maxLength *int     // Maximum length of a string in bytes or of an array
+}
+
+// parseXrpcTag returns the parameter name and options of a request struct field.
+func parseXrpcTag(field reflect.StructField) (string, xrpcTagOptions) {
+	var opts xrpcTagOptions
+
+	tag, ok := field.Tag.Lookup("xrpc")
+	if !ok {
+		return camelCase(field.Name), opts
+	}
+	name, rest, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = camelCase(field.Name)
+	}
+	for _, opt := range strings.Split(rest, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "omitempty":
+			opts.omitempty = true
+		case "datetime":
+			opts.datetime = true
+		case "required":
+			opts.required = true
+		case "format":
+			opts.format = value
+		case "enum":
+			opts.enum = strings.Split(value, "|")
+		case "minimum":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				opts.minimum = &n
+			}
+		case "maximum":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				opts.maximum = &n
+			}
+		case "maxLength":
+			if n, err := strconv.Atoi(value); err == nil {
+				opts.maxLength = &n
+			}
+		}
+	}
+	return name, opts
+}
+
+// encodeParam turns a field into the value for the xrpc params map: a string
+// for single values, a []string for repeated ones, or nil to leave it out.
+func encodeParam(v reflect.Value, opts xrpcTagOptions) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, err := encodeScalar(v.Index(i), opts)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return encodeScalar(v, opts)
+}
+
+// encodeScalar formats a single parameter value.
+func encodeScalar(v reflect.Value, opts xrpcTagOptions) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if opts.datetime {
+			return t.UTC().Format(syntax.AtprotoDatetimeLayout), nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported parameter type %s", v.Type())
+}
+
+// camelCase converts an exported Go field name to the lexicon's camelCase,
+// e.g. "Cursor" to "cursor" and "URLPrefix" to "urlPrefix".
+func camelCase(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		// Keep the start of the next word in an acronym run uppercase
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// FieldError describes a request field violating the lexicon of its endpoint.
+type FieldError struct {
+	Field  string      // Name of the Go struct field
+	Param  string      // Name of the XRPC parameter
+	Value  interface{} // Offending value
+	Reason string      // Constraint that was violated
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Param, e.Reason)
+}
+
+// ValidationError is returned from API calls whose request does not satisfy the
+// lexicon of the endpoint. It lists every offending field, and is returned
+// before anything is sent to the server.
+//
+// ValidationError matches ErrInvalidRequest with errors.Is.
+type ValidationError struct {
+	Method string       // NSID of the XRPC method the request is for, e.g. "app.bsky.feed.searchPosts"
+	Fields []FieldError // Every field violating the lexicon, in declaration order
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.Error()
+	}
+	return fmt.Sprintf("%v for %s: %s", ErrInvalidRequest, e.Method, strings.Join(reasons, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// validateRequest checks a request struct against the lexicon constraints in
+// its `xrpc` struct tags, returning a *ValidationError if any field violates
+// them. Optional fields holding their zero value are not checked.
+func validateRequest(method string, request any) error {
+	v := reflect.ValueOf(request)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.New(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return errors.New("Tried to validate a non-struct type, returning error.")
+	}
+
+	verr := &ValidationError{Method: method}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		name, opts := parseXrpcTag(structField)
+		if name == "-" {
+			continue
+		}
+		if field.IsZero() {
+			if opts.required {
+				verr.Fields = append(verr.Fields, FieldError{Field: structField.Name, Param: name, Value: field.Interface(), Reason: "is required"})
+			}
+			continue
+		}
+		if reason := checkConstraints(field, opts); reason != "" {
+			verr.Fields = append(verr.Fields, FieldError{Field: structField.Name, Param: name, Value: field.Interface(), Reason: reason})
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// checkConstraints returns the first lexicon constraint the value violates, or
+// an empty string if it satisfies all of them.
+func checkConstraints(v reflect.Value, opts xrpcTagOptions) string {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if opts.maxLength != nil && len(s) > *opts.maxLength {
+			return fmt.Sprintf("must be at most %d bytes long", *opts.maxLength)
+		}
+		if len(opts.enum) > 0 && !slices.Contains(opts.enum, s) {
+			return fmt.Sprintf("must be one of %s", strings.Join(opts.enum, ", "))
+		}
+		if err := checkFormat(opts.format, s); err != nil {
+			return fmt.Sprintf("must be a valid %s: %v", opts.format, err)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		if opts.minimum != nil && n < *opts.minimum {
+			return fmt.Sprintf("must be at least %d", *opts.minimum)
+		}
+		if opts.maximum != nil && n > *opts.maximum {
+			return fmt.Sprintf("must be at most %d", *opts.maximum)
+		}
+	case reflect.Slice:
+		if opts.maxLength != nil && v.Len() > *opts.maxLength {
+			return fmt.Sprintf("must have at most %d elements", *opts.maxLength)
+		}
+		elemOpts := xrpcTagOptions{format: opts.format, enum: opts.enum}
+		for i := 0; i < v.Len(); i++ {
+			if reason := checkConstraints(v.Index(i), elemOpts); reason != "" {
+				return fmt.Sprintf("element %d %s", i, reason)
+			}
+		}
+	}
+	return ""
+}
+
+// checkFormat validates a string against one of the lexicon string formats.
+// Unknown formats are not checked.
+func checkFormat(format string, s string) error {
+	var err error
+	switch format {
+	case "at-identifier":
+		_, err = syntax.ParseAtIdentifier(s)
+	case "at-uri":
+		_, err = syntax.ParseATURI(s)
+	case "cid":
+		_, err = syntax.ParseCID(s)
+	case "datetime":
+		_, err = syntax.ParseDatetime(s)
+	case "did":
+		_, err = syntax.ParseDID(s)
+	case "handle":
+		_, err = syntax.ParseHandle(s)
+	case "language":
+		_, err = syntax.ParseLanguage(s)
+	case "nsid":
+		_, err = syntax.ParseNSID(s)
+	case "record-key":
+		_, err = syntax.ParseRecordKey(s)
+	case "tid":
+		_, err = syntax.ParseTID(s)
+	case "uri":
+		_, err = syntax.ParseURI(s)
+	}
+	return err
+}
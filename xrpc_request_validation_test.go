@@ -0,0 +1,148 @@
+package bluesky
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests the exact query string sent for each field of a search request.
+func TestSearchPostsQueryEncoding(t *testing.T) {
+	since := time.Date(2024, 11, 5, 9, 30, 15, 123000000, time.FixedZone("CET", 3600))
+	until := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		request *SearchPostsRequest
+		query   string
+	}{
+		{"q", &SearchPostsRequest{Q: "grilled cheese & ham"}, "q=grilled+cheese+%26+ham"},
+		{"author", &SearchPostsRequest{Q: "a", Author: "alice.bsky.social"}, "author=alice.bsky.social&q=a"},
+		{"cursor", &SearchPostsRequest{Q: "a", Cursor: "25"}, "cursor=25&q=a"},
+		{"domain", &SearchPostsRequest{Q: "a", Domain: "example.com"}, "domain=example.com&q=a"},
+		{"lang", &SearchPostsRequest{Q: "a", Lang: "pt-BR"}, "lang=pt-BR&q=a"},
+		{"limit", &SearchPostsRequest{Q: "a", Limit: 100}, "limit=100&q=a"},
+		{"mentions", &SearchPostsRequest{Q: "a", Mentions: "did:plc:test"}, "mentions=did%3Aplc%3Atest&q=a"},
+		{"since", &SearchPostsRequest{Q: "a", Since: since}, "q=a&since=2024-11-05T08%3A30%3A15.123Z"},
+		{"sort", &SearchPostsRequest{Q: "a", Sort: "latest"}, "q=a&sort=latest"},
+		{"tag", &SearchPostsRequest{Q: "a", Tag: []string{"go", "bluesky"}}, "q=a&tag=go&tag=bluesky"},
+		{"until", &SearchPostsRequest{Q: "a", Until: until}, "q=a&until=2025-01-01T00%3A00%3A00Z"},
+		{"url", &SearchPostsRequest{Q: "a", Url: "https://example.com/?a=b"}, "q=a&url=https%3A%2F%2Fexample.com%2F%3Fa%3Db"},
+	}
+
+	var query string
+	mockTransport := newDefaultMockRoundTripper()
+	mockTransport.responseFuncs["/xrpc/app.bsky.feed.searchPosts"] = func(req *http.Request) *http.Response {
+		query = req.URL.RawQuery
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"posts": []}`)),
+		}
+	}
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := c.SearchPosts(context.Background(), test.request)
+			assert.NoError(t, err)
+			assert.Equal(t, test.query, query)
+		})
+	}
+}
+
+// Tests the encoding of tag options and untagged fields.
+func TestGetParamMap(t *testing.T) {
+	type request struct {
+		Untagged  string
+		URLPrefix string
+		Skipped   string    `xrpc:"-"`
+		Enabled   bool      `xrpc:"enabled"`
+		Count     *int      `xrpc:"count,omitempty"`
+		Ints      []int     `xrpc:"ints,omitempty"`
+		At        time.Time `xrpc:"at,omitempty"`
+		hidden    string
+	}
+	zero := 0
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", -7200))
+
+	params, err := getParamMap(&request{Untagged: "a", URLPrefix: "b", Skipped: "c", Count: &zero, Ints: []int{1, 2}, At: at, hidden: "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"untagged":  "a",
+		"urlPrefix": "b",
+		"enabled":   "false",
+		"count":     "0",
+		"ints":      []string{"1", "2"},
+		"at":        "2024-01-02T03:04:05-02:00",
+	}, params)
+
+	_, err = getParamMap("not a struct")
+	assert.Error(t, err)
+
+	_, err = getParamMap(struct{ C chan int }{})
+	assert.Error(t, err)
+}
+
+// Tests that invalid requests are rejected with every offending field, before
+// anything is sent to the server.
+func TestSearchPostsValidation(t *testing.T) {
+	mockTransport := newSearchPagesRoundTripper(map[string]string{"": `{"posts": []}`})
+	c := newMockClient(t, mockTransport)
+	defer c.Close()
+
+	_, err := c.SearchPosts(context.Background(), &SearchPostsRequest{
+		Author:   "not a handle",
+		Lang:     "not_a_language",
+		Limit:    101,
+		Mentions: "did:plc:test",
+		Sort:     "oldest",
+		Url:      "example.com",
+	})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, "app.bsky.feed.searchPosts", verr.Method)
+		var params []string
+		for _, field := range verr.Fields {
+			params = append(params, field.Param)
+		}
+		assert.Equal(t, []string{"author", "lang", "limit", "q", "sort", "url"}, params)
+	}
+	_, err = c.SearchPosts(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Equal(t, 0, mockTransport.calls("/xrpc/app.bsky.feed.searchPosts"))
+
+	_, err = c.SearchPosts(context.Background(), &SearchPostsRequest{Q: "a", Author: "alice.bsky.social", Lang: "pt-BR", Limit: 1, Sort: "top", Url: "https://example.com"})
+	assert.NoError(t, err)
+}
+
+// Tests the lexicon constraints supported in struct tags.
+func TestValidateRequest(t *testing.T) {
+	type request struct {
+		Required string   `xrpc:"required,required"`
+		Min      int      `xrpc:"min,minimum=0"`
+		Short    string   `xrpc:"short,maxLength=3"`
+		Few      []string `xrpc:"few,maxLength=2"`
+		Dids     []string `xrpc:"dids,format=did"`
+		Enum     []string `xrpc:"enum,enum=a|b"`
+	}
+
+	err := validateRequest("test", &request{Required: "x", Min: 1, Short: "abc", Few: []string{"a"}, Dids: []string{"did:plc:test"}, Enum: []string{"b"}})
+	assert.NoError(t, err)
+
+	err = validateRequest("test", &request{Min: -1, Short: "abcd", Few: []string{"a", "b", "c"}, Dids: []string{"did:plc:test", "alice"}, Enum: []string{"c"}})
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Len(t, verr.Fields, 6)
+		assert.Equal(t, "Min", verr.Fields[1].Field)
+		assert.Equal(t, -1, verr.Fields[1].Value)
+		assert.Equal(t, "must be at least 0", verr.Fields[1].Reason)
+	}
+}